@@ -0,0 +1,229 @@
+package huaweicloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// HeaderXAuthToken carries an IAM token in place of an AK/SK signature.
+	HeaderXAuthToken = "X-Auth-Token"
+	// HeaderXSubjectToken carries the token issued by IAM.
+	HeaderXSubjectToken = "X-Subject-Token"
+
+	// tokenRefreshWindow is how long before expiry IAM tokens are renewed.
+	tokenRefreshWindow = 10 * time.Minute
+)
+
+// Authenticator authenticates API requests. It is called for every attempt
+// of a request, right before it is sent.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// SignerAuthenticator signs requests with AK/SK credentials.
+type SignerAuthenticator struct {
+	Credentials CredentialsProvider
+}
+
+func (a SignerAuthenticator) Authenticate(req *http.Request) error {
+	creds, err := a.Credentials.Retrieve(req.Context())
+	if err != nil {
+		return err
+	}
+	signer := &Signer{
+		Key:           creds.AccessKeyId,
+		Secret:        creds.SecretAccessKey,
+		SecurityToken: creds.SecurityToken,
+	}
+	return signer.Sign(req)
+}
+
+// TokenAuthenticator authenticates requests with an IAM token sent as the
+// X-Auth-Token header. The token is either given directly, e.g. a federated
+// token, or obtained from IAM with a password and renewed shortly before
+// it expires.
+type TokenAuthenticator struct {
+	// Token is a pre-issued token. When set, no password login is made.
+	Token string
+
+	// Endpoint is the IAM endpoint, e.g. "https://iam.cn-south-1.myhuaweicloud.com".
+	Endpoint string
+	// DomainName is the account the user belongs to.
+	DomainName string
+	// UserName and Password are the IAM user's credentials.
+	UserName string
+	Password string
+	// ProjectId or ProjectName select the project the token is scoped to.
+	// ProjectName is usually the region, e.g. "cn-south-1".
+	ProjectId   string
+	ProjectName string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	now     func() time.Time
+}
+
+func (a *TokenAuthenticator) Authenticate(req *http.Request) error {
+	token := a.Token
+	if token == "" {
+		var err error
+		token, err = a.getToken(req.Context())
+		if err != nil {
+			return err
+		}
+	}
+	req.Header.Set(HeaderXAuthToken, token)
+	return nil
+}
+
+// getToken returns the cached token, logging in again if it is missing or
+// about to expire.
+func (a *TokenAuthenticator) getToken(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now
+	if a.now != nil {
+		now = a.now
+	}
+	if a.token != "" && now().Before(a.expires.Add(-tokenRefreshWindow)) {
+		return a.token, nil
+	}
+
+	token, expires, err := a.login(ctx)
+	if err != nil {
+		return "", err
+	}
+	a.token, a.expires = token, expires
+	return token, nil
+}
+
+// invalidateToken drops the cached token if it is the given one, which IAM
+// rejected, so that the next request logs in again. It reports whether a
+// new token can be obtained, which is not the case for a pre-issued token.
+func (a *TokenAuthenticator) invalidateToken(token string) bool {
+	if a.Token != "" {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Another request may already have logged in again.
+	if a.token == token {
+		a.token = ""
+	}
+	return true
+}
+
+type tokenRequest struct {
+	Auth tokenAuth `json:"auth"`
+}
+
+type tokenAuth struct {
+	Identity tokenIdentity `json:"identity"`
+	Scope    tokenScope    `json:"scope"`
+}
+
+type tokenIdentity struct {
+	Methods  []string      `json:"methods"`
+	Password tokenPassword `json:"password"`
+}
+
+type tokenPassword struct {
+	User tokenUser `json:"user"`
+}
+
+type tokenUser struct {
+	Name     string    `json:"name"`
+	Password string    `json:"password"`
+	Domain   tokenName `json:"domain"`
+}
+
+type tokenScope struct {
+	Project tokenName `json:"project"`
+}
+
+type tokenName struct {
+	Id   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type tokenResponse struct {
+	Token struct {
+		ExpiresAt string `json:"expires_at"`
+	} `json:"token"`
+}
+
+// login obtains a project-scoped token from IAM with the user's password.
+func (a *TokenAuthenticator) login(ctx context.Context) (string, time.Time, error) {
+	if a.Endpoint == "" || a.DomainName == "" || a.UserName == "" || a.Password == "" {
+		return "", time.Time{}, fmt.Errorf("iam: endpoint, domain name, user name and password are required")
+	}
+
+	scope := tokenName{Id: a.ProjectId}
+	if scope.Id == "" {
+		scope.Name = a.ProjectName
+	}
+	body, err := json.Marshal(tokenRequest{Auth: tokenAuth{
+		Identity: tokenIdentity{
+			Methods: []string{"password"},
+			Password: tokenPassword{User: tokenUser{
+				Name:     a.UserName,
+				Password: a.Password,
+				Domain:   tokenName{Name: a.DomainName},
+			}},
+		},
+		Scope: tokenScope{Project: scope},
+	}})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	url := strings.TrimSuffix(a.Endpoint, "/") + "/v3/auth/tokens"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/json;charset=utf8")
+
+	httpClient := a.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return "", time.Time{}, newAPIError(resp, body)
+	}
+
+	token := resp.Header.Get(HeaderXSubjectToken)
+	if token == "" {
+		return "", time.Time{}, fmt.Errorf("iam: response has no %s header", HeaderXSubjectToken)
+	}
+	var result tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", time.Time{}, fmt.Errorf("iam: %v", err)
+	}
+	expires, err := time.Parse(time.RFC3339Nano, result.Token.ExpiresAt)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("iam: invalid expires_at %q: %v", result.Token.ExpiresAt, err)
+	}
+	return token, expires, nil
+}
@@ -0,0 +1,121 @@
+package huaweicloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenAuthenticator(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var logins int
+	iam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/auth/tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		var body tokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding token request: %v", err)
+		}
+		user := body.Auth.Identity.Password.User
+		if user.Name != "user" || user.Password != "password" || user.Domain.Name != "domain" {
+			t.Errorf("unexpected user %+v", user)
+		}
+		if body.Auth.Scope.Project.Name != "cn-south-1" {
+			t.Errorf("unexpected scope %+v", body.Auth.Scope)
+		}
+
+		logins++
+		w.Header().Set(HeaderXSubjectToken, fmt.Sprintf("token-%d", logins))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":{"expires_at":%q}}`, now.Add(time.Hour).Format(time.RFC3339Nano))
+	}))
+	defer iam.Close()
+
+	var tokens []string
+	dns := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderXAuthorization) != "" {
+			t.Errorf("expected no AK/SK signature with token authentication")
+		}
+		tokens = append(tokens, r.Header.Get(HeaderXAuthToken))
+		_ = json.NewEncoder(w).Encode(ListZonesResponse{})
+	}))
+	defer dns.Close()
+
+	auth := &TokenAuthenticator{
+		Endpoint:    iam.URL,
+		DomainName:  "domain",
+		UserName:    "user",
+		Password:    "password",
+		ProjectName: "cn-south-1",
+		now:         func() time.Time { return now },
+	}
+	client := NewClient("", "", "", WithEndpoint(dns.URL), WithAuthenticator(auth))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListZones(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	now = now.Add(time.Hour - tokenRefreshWindow)
+	if _, err := client.ListZones(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"token-1", "token-1", "token-2"}
+	if fmt.Sprint(tokens) != fmt.Sprint(expected) {
+		t.Errorf("expected tokens %v, got %v", expected, tokens)
+	}
+}
+
+func TestTokenAuthenticatorRejectedToken(t *testing.T) {
+	var logins int
+	iam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logins++
+		w.Header().Set(HeaderXSubjectToken, fmt.Sprintf("token-%d", logins))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":{"expires_at":%q}}`, time.Now().Add(time.Hour).Format(time.RFC3339Nano))
+	}))
+	defer iam.Close()
+
+	// The first token is revoked before it expires.
+	var tokens []string
+	dns := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(HeaderXAuthToken)
+		tokens = append(tokens, token)
+		if token == "token-1" || token == "revoked" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":"APIGW.0301","message":"incorrect IAM authentication information"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ListZonesResponse{})
+	}))
+	defer dns.Close()
+
+	auth := &TokenAuthenticator{Endpoint: iam.URL, DomainName: "domain", UserName: "user", Password: "password"}
+	client := NewClient("", "", "", WithEndpoint(dns.URL), WithAuthenticator(auth),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if _, err := client.ListZones(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"token-1", "token-2"}
+	if fmt.Sprint(tokens) != fmt.Sprint(expected) || logins != 2 {
+		t.Errorf("expected to log in again once, got tokens %v after %d logins", tokens, logins)
+	}
+
+	// A pre-issued token cannot be renewed, so the error is returned.
+	tokens = nil
+	client = NewClient("", "", "", WithEndpoint(dns.URL), WithAuthenticator(&TokenAuthenticator{Token: "revoked"}))
+	if _, err := client.ListZones(context.Background()); !isStatus(err, http.StatusUnauthorized) {
+		t.Errorf("expected the 401 to be returned, got %v", err)
+	}
+	if len(tokens) != 1 {
+		t.Errorf("expected a single attempt with a pre-issued token, got %v", tokens)
+	}
+}
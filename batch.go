@@ -0,0 +1,293 @@
+package huaweicloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// defaultBatchThreshold is the number of recordset updates or deletions
+	// from which the provider uses the batch endpoints.
+	defaultBatchThreshold = 5
+	// maxBatchSize bounds the number of recordsets in one batch request.
+	maxBatchSize = 100
+
+	// codeAPINotFound is returned by the API gateway for endpoints it does
+	// not expose.
+	codeAPINotFound = "APIGW.0101"
+	// codeUnsupportedZone is returned by the v2.1 API for zones it does not
+	// manage, such as private zones.
+	codeUnsupportedZone = "DNS.0002"
+)
+
+// BatchDeleteRecords deletes the recordsets with the given IDs in a single
+// request and returns them. The request fails as a whole.
+func (c *Client) BatchDeleteRecords(ctx context.Context, zone string, recordIds []string) ([]RecordSet, error) {
+	body, err := json.Marshal(batchDeleteRequest{RecordsetIds: recordIds})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(ListRecordsResponse)
+	err = c.withZoneId(ctx, zone, func(zoneId string) error {
+		url := c.getVersionURL("v2.1")
+		url = url.JoinPath("zones", zoneId, "recordsets")
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url.String(), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		return c.doAPIRequest(req, resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.RecordSets, nil
+}
+
+// BatchUpdateRecords updates the recordsets in a single request and returns
+// them. Only the ID, name, type, TTL, values, description and weight of
+// each recordset are sent. The request fails as a whole.
+func (c *Client) BatchUpdateRecords(ctx context.Context, zone string, records []RecordSet) ([]RecordSet, error) {
+	update := batchUpdateRequest{RecordSets: make([]RecordSet, 0, len(records))}
+	for _, rs := range records {
+		update.RecordSets = append(update.RecordSets, RecordSet{
+			Id:          rs.Id,
+			Name:        rs.Name,
+			Type:        rs.Type,
+			Ttl:         rs.Ttl,
+			Records:     rs.Records,
+			Description: rs.Description,
+			Weight:      rs.Weight,
+		})
+	}
+	body, err := json.Marshal(update)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(ListRecordsResponse)
+	err = c.withZoneId(ctx, zone, func(zoneId string) error {
+		url := c.getVersionURL("v2.1")
+		url = url.JoinPath("zones", zoneId, "recordsets")
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url.String(), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		return c.doAPIRequest(req, resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.RecordSets, nil
+}
+
+// BatchSetRecordStatus enables or disables the recordsets with the given IDs
+// in a single request, with status StatusEnable or StatusDisable.
+func (c *Client) BatchSetRecordStatus(ctx context.Context, recordIds []string, status string) ([]RecordSet, error) {
+	body, err := json.Marshal(batchStatusRequest{Status: status, RecordsetIds: recordIds})
+	if err != nil {
+		return nil, err
+	}
+
+	url := c.getVersionURL("v2.1")
+	url = url.JoinPath("recordsets", "statuses", "set")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(ListRecordsResponse)
+	if err = c.doAPIRequest(req, resp); err != nil {
+		return nil, err
+	}
+
+	return resp.RecordSets, nil
+}
+
+// BatchResult is the outcome of the change of one recordset.
+type BatchResult struct {
+	// RecordSet is the changed recordset as returned by the API, or the
+	// requested one if the change failed.
+	RecordSet RecordSet
+	// Deleted is true if the recordset was to be deleted.
+	Deleted bool
+	// Err is nil if the change succeeded.
+	Err error
+}
+
+// BatchError is returned by the provider when some recordset changes fail.
+// Results holds the outcome of every change, including the successful ones,
+// whose records are returned along with the error.
+type BatchError struct {
+	Results []BatchResult
+}
+
+func (e *BatchError) Error() string {
+	var failed []string
+	for _, r := range e.Results {
+		if r.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s %s: %v", r.RecordSet.Name, r.RecordSet.Type, r.Err))
+		}
+	}
+	return fmt.Sprintf("%d of %d recordset changes failed: %s", len(failed), len(e.Results), strings.Join(failed, "; "))
+}
+
+// Unwrap returns the errors of the failed changes.
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, r := range e.Results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return errs
+}
+
+// recordChange is a recordset to create, update or delete.
+type recordChange struct {
+	rs      RecordSet
+	create  bool
+	deleted bool
+}
+
+// applyChanges applies the changes and returns their outcome in the same
+// order. Updates and deletions go through the batch endpoints when there
+// are enough of them, falling back to one call per recordset if a batch
+// request fails. Creations are always made one by one.
+func (p *Provider) applyChanges(ctx context.Context, client *Client, zone string, changes []recordChange) []BatchResult {
+	results := make([]BatchResult, len(changes))
+	var updates, deletes []int
+	for i, c := range changes {
+		results[i] = BatchResult{RecordSet: c.rs, Deleted: c.deleted}
+		switch {
+		case c.create:
+			results[i] = p.applyChange(ctx, client, zone, c)
+		case c.deleted:
+			deletes = append(deletes, i)
+		default:
+			updates = append(updates, i)
+		}
+	}
+
+	threshold := p.BatchThreshold
+	if threshold == 0 {
+		threshold = defaultBatchThreshold
+	}
+	for _, indexes := range [][]int{updates, deletes} {
+		if threshold < 0 || len(indexes) < threshold {
+			for _, i := range indexes {
+				results[i] = p.applyChange(ctx, client, zone, changes[i])
+			}
+			continue
+		}
+		for len(indexes) > 0 {
+			n := len(indexes)
+			if n > maxBatchSize {
+				n = maxBatchSize
+			}
+			p.applyBatch(ctx, client, zone, changes, indexes[:n], results)
+			indexes = indexes[n:]
+		}
+	}
+
+	return results
+}
+
+// applyBatch applies the changes at indexes, which are all updates or all
+// deletions, in a single request.
+func (p *Provider) applyBatch(ctx context.Context, client *Client, zone string, changes []recordChange, indexes []int, results []BatchResult) {
+	var resp []RecordSet
+	var err error
+	if changes[indexes[0]].deleted {
+		ids := make([]string, 0, len(indexes))
+		for _, i := range indexes {
+			ids = append(ids, changes[i].rs.Id)
+		}
+		resp, err = client.BatchDeleteRecords(ctx, zone, ids)
+	} else {
+		records := make([]RecordSet, 0, len(indexes))
+		for _, i := range indexes {
+			records = append(records, changes[i].rs)
+		}
+		resp, err = client.BatchUpdateRecords(ctx, zone, records)
+	}
+	if err != nil {
+		if ctx.Err() == nil && batchUnavailable(err) {
+			for _, i := range indexes {
+				results[i] = p.applyChange(ctx, client, zone, changes[i])
+			}
+			return
+		}
+		// Any other failure would recur for every recordset.
+		for _, i := range indexes {
+			results[i].Err = err
+		}
+		return
+	}
+
+	returned := make(map[string]RecordSet, len(resp))
+	for _, rs := range resp {
+		returned[rs.Id] = rs
+	}
+	for _, i := range indexes {
+		rs, ok := returned[changes[i].rs.Id]
+		if !ok {
+			results[i].Err = fmt.Errorf("recordset %s missing from the batch response", changes[i].rs.Id)
+			continue
+		}
+		if !changes[i].deleted {
+			results[i].RecordSet = rs
+		}
+	}
+}
+
+// applyChange applies a single change.
+func (p *Provider) applyChange(ctx context.Context, client *Client, zone string, c recordChange) BatchResult {
+	result := BatchResult{RecordSet: c.rs, Deleted: c.deleted}
+
+	var resp *RecordSet
+	switch {
+	case c.create:
+		resp, result.Err = client.AppendRecord(ctx, zone, c.rs)
+	case c.deleted:
+		_, result.Err = client.DeleteRecord(ctx, zone, c.rs.Id)
+	default:
+		resp, result.Err = client.UpdateRecord(ctx, zone, c.rs)
+	}
+	if result.Err == nil && resp != nil {
+		result.RecordSet = *resp
+	}
+	return result
+}
+
+// batchUnavailable reports whether a batch request failed because the
+// batch endpoint cannot serve it, so that the changes can be made one by
+// one instead: the API gateway does not expose the endpoint, or the v2.1
+// API rejects the zone, as it does private zones.
+func batchUnavailable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return (apiErr.StatusCode == http.StatusNotFound && apiErr.Code == codeAPINotFound) ||
+		apiErr.Code == codeUnsupportedZone
+}
+
+// batchError returns a *BatchError if any of the changes failed.
+func batchError(results []BatchResult) error {
+	for _, r := range results {
+		if r.Err != nil {
+			return &BatchError{Results: results}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package huaweicloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestProviderBatchDelete(t *testing.T) {
+	tests := []struct {
+		name string
+		// batch is the status of batch requests, 0 for success.
+		batch        int
+		failing      string
+		batchDeletes int
+		deletes      int
+	}{
+		{name: "batch", batchDeletes: 1},
+		{name: "fallback", batch: http.StatusBadRequest, batchDeletes: 1, deletes: 6},
+		{name: "partial failure", batch: http.StatusBadRequest, failing: "rs-3", batchDeletes: 1, deletes: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var batchDeletes, deletes int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				switch {
+				case r.URL.Path == "/v2/zones":
+					_ = json.NewEncoder(w).Encode(ListZonesResponse{Zones: []Zone{{Id: "zone-id", Name: "example.com."}}})
+				case r.URL.Path == "/v2/zones/zone-id/recordsets" && r.Method == http.MethodGet:
+					name := r.URL.Query().Get("name")
+					id := "rs-" + strings.TrimPrefix(strings.TrimSuffix(name, ".example.com."), "host")
+					_ = json.NewEncoder(w).Encode(ListRecordsResponse{RecordSets: []RecordSet{
+						{Id: id, Name: name, Type: "TXT", Ttl: 300, Records: []string{`"value"`}},
+					}})
+				case r.URL.Path == "/v2.1/zones/zone-id/recordsets" && r.Method == http.MethodDelete:
+					batchDeletes++
+					if tt.batch != 0 {
+						w.WriteHeader(tt.batch)
+						_, _ = w.Write([]byte(`{"code":"DNS.0002","message":"unsupported"}`))
+						return
+					}
+					var req batchDeleteRequest
+					_ = json.NewDecoder(r.Body).Decode(&req)
+					var resp ListRecordsResponse
+					for _, id := range req.RecordsetIds {
+						resp.RecordSets = append(resp.RecordSets, RecordSet{Id: id, Status: StatusPendingDelete})
+					}
+					_ = json.NewEncoder(w).Encode(resp)
+				case strings.HasPrefix(r.URL.Path, "/v2/zones/zone-id/recordsets/") && r.Method == http.MethodDelete:
+					deletes++
+					if path.Base(r.URL.Path) == tt.failing {
+						w.WriteHeader(http.StatusBadRequest)
+						_, _ = w.Write([]byte(`{"code":"DNS.0001","message":"bad request"}`))
+						return
+					}
+					_ = json.NewEncoder(w).Encode(RecordSet{Id: path.Base(r.URL.Path)})
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			var records []libdns.Record
+			for i := 0; i < 6; i++ {
+				records = append(records, libdns.TXT{Name: fmt.Sprintf("host%d", i), Text: "value"})
+			}
+
+			p := &Provider{AccessKeyId: "ak", SecretAccessKey: "sk", Endpoint: server.URL}
+			deleted, err := p.DeleteRecords(context.Background(), "example.com.", records)
+
+			var batchErr *BatchError
+			if tt.failing == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(deleted) != 6 {
+					t.Errorf("expected 6 deleted records, got %d", len(deleted))
+				}
+			} else {
+				if !errors.As(err, &batchErr) {
+					t.Fatalf("expected a *BatchError, got %v", err)
+				}
+				var failed []string
+				for _, result := range batchErr.Results {
+					if result.Err != nil {
+						failed = append(failed, result.RecordSet.Id)
+					}
+				}
+				if len(batchErr.Results) != 6 || fmt.Sprint(failed) != "["+tt.failing+"]" {
+					t.Errorf("unexpected results %+v", batchErr.Results)
+				}
+				if len(deleted) != 5 {
+					t.Errorf("expected the 5 successfully deleted records, got %d", len(deleted))
+				}
+			}
+			if batchDeletes != tt.batchDeletes || deletes != tt.deletes {
+				t.Errorf("expected %d batch and %d single deletes, got %d and %d", tt.batchDeletes, tt.deletes, batchDeletes, deletes)
+			}
+		})
+	}
+}
+
+func TestClientBatchUpdateRecords(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/zones":
+			_ = json.NewEncoder(w).Encode(ListZonesResponse{Zones: []Zone{{Id: "zone-id", Name: "example.com."}}})
+		case r.URL.Path == "/v2.1/zones/zone-id/recordsets" && r.Method == http.MethodPut:
+			var req batchUpdateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("decoding batch update: %v", err)
+			}
+			for i := range req.RecordSets {
+				req.RecordSets[i].Status = StatusPendingUpdate
+			}
+			_ = json.NewEncoder(w).Encode(ListRecordsResponse{RecordSets: req.RecordSets})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	weight := int32(10)
+	client := NewClient("ak", "sk", "", WithEndpoint(server.URL))
+	updated, err := client.BatchUpdateRecords(context.Background(), "example.com.", []RecordSet{
+		{Id: "rs-1", Name: "www.example.com.", Type: "A", Ttl: 600, Records: []string{"192.0.2.1"}, Status: StatusActive, Line: "Dianxin"},
+		{Id: "rs-2", Name: "mail.example.com.", Type: "A", Ttl: 600, Records: []string{"192.0.2.2"}, Weight: &weight},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Fields such as the status and line are not sent.
+	expected := []RecordSet{
+		{Id: "rs-1", Name: "www.example.com.", Type: "A", Ttl: 600, Records: []string{"192.0.2.1"}, Status: StatusPendingUpdate},
+		{Id: "rs-2", Name: "mail.example.com.", Type: "A", Ttl: 600, Records: []string{"192.0.2.2"}, Weight: &weight, Status: StatusPendingUpdate},
+	}
+	if !reflect.DeepEqual(updated, expected) {
+		t.Errorf("expected %+v, got %+v", expected, updated)
+	}
+}
+
+func TestClientBatchSetRecordStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2.1/recordsets/statuses/set" || r.Method != http.MethodPut {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		var req batchStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding batch status: %v", err)
+		}
+		if req.Status != StatusDisable {
+			t.Errorf("expected status %s, got %s", StatusDisable, req.Status)
+		}
+		var resp ListRecordsResponse
+		for _, id := range req.RecordsetIds {
+			resp.RecordSets = append(resp.RecordSets, RecordSet{Id: id, Status: req.Status})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient("ak", "sk", "", WithEndpoint(server.URL))
+	records, err := client.BatchSetRecordStatus(context.Background(), []string{"rs-1", "rs-2"}, StatusDisable)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 2 || records[0].Id != "rs-1" || records[1].Id != "rs-2" || records[1].Status != StatusDisable {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
+
+func TestProviderApplyChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		// batch and code are the status and error code of batch updates,
+		// 0 for success.
+		batch int
+		code  string
+		// omit is the recordset left out of a successful batch response.
+		omit    string
+		failing string
+		updates int
+		failed  []string
+	}{
+		{name: "batch"},
+		{name: "missing from batch response", omit: "rs-2", failed: []string{"rs-2"}},
+		{name: "fallback", batch: http.StatusBadRequest, code: "DNS.0002", updates: 3},
+		{name: "fallback without endpoint", batch: http.StatusNotFound, code: "APIGW.0101", updates: 3},
+		{name: "fallback with failure", batch: http.StatusBadRequest, code: "DNS.0002", failing: "rs-1", updates: 3, failed: []string{"rs-1"}},
+		{name: "batch rejected", batch: http.StatusForbidden, code: "DNS.0030", failed: []string{"rs-1", "rs-2", "rs-3"}},
+		{name: "invalid batch", batch: http.StatusBadRequest, code: "DNS.0308", failed: []string{"rs-1", "rs-2", "rs-3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var updates, creates int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				switch {
+				case r.URL.Path == "/v2/zones":
+					_ = json.NewEncoder(w).Encode(ListZonesResponse{Zones: []Zone{{Id: "zone-id", Name: "example.com."}}})
+				case r.URL.Path == "/v2.1/zones/zone-id/recordsets" && r.Method == http.MethodPut:
+					if tt.batch != 0 {
+						w.WriteHeader(tt.batch)
+						fmt.Fprintf(w, `{"code":%q,"message":"batch failed"}`, tt.code)
+						return
+					}
+					var req batchUpdateRequest
+					_ = json.NewDecoder(r.Body).Decode(&req)
+					var resp ListRecordsResponse
+					for _, rs := range req.RecordSets {
+						if rs.Id != tt.omit {
+							rs.Status = StatusPendingUpdate
+							resp.RecordSets = append(resp.RecordSets, rs)
+						}
+					}
+					_ = json.NewEncoder(w).Encode(resp)
+				case strings.HasPrefix(r.URL.Path, "/v2/zones/zone-id/recordsets/") && r.Method == http.MethodPut:
+					updates++
+					if path.Base(r.URL.Path) == tt.failing {
+						w.WriteHeader(http.StatusBadRequest)
+						_, _ = w.Write([]byte(`{"code":"DNS.0001","message":"bad request"}`))
+						return
+					}
+					var rs RecordSet
+					_ = json.NewDecoder(r.Body).Decode(&rs)
+					rs.Id, rs.Status = path.Base(r.URL.Path), StatusPendingUpdate
+					_ = json.NewEncoder(w).Encode(rs)
+				case r.URL.Path == "/v2/zones/zone-id/recordsets" && r.Method == http.MethodPost:
+					creates++
+					var rs RecordSet
+					_ = json.NewDecoder(r.Body).Decode(&rs)
+					rs.Id, rs.Status = "rs-new", StatusPendingCreate
+					_ = json.NewEncoder(w).Encode(rs)
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			// Creations are never batched, and the results keep the order
+			// of the changes.
+			changes := []recordChange{
+				{rs: RecordSet{Id: "rs-1", Name: "a.example.com.", Type: "A", Records: []string{"192.0.2.1"}}},
+				{rs: RecordSet{Name: "new.example.com.", Type: "A", Records: []string{"192.0.2.9"}}, create: true},
+				{rs: RecordSet{Id: "rs-2", Name: "b.example.com.", Type: "A", Records: []string{"192.0.2.2"}}},
+				{rs: RecordSet{Id: "rs-3", Name: "c.example.com.", Type: "A", Records: []string{"192.0.2.3"}}},
+			}
+			p := &Provider{AccessKeyId: "ak", SecretAccessKey: "sk", Endpoint: server.URL, BatchThreshold: 3}
+			client, err := p.getClient()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			results := p.applyChanges(context.Background(), client, "example.com.", changes)
+
+			if len(results) != len(changes) {
+				t.Fatalf("expected %d results, got %d", len(changes), len(results))
+			}
+			var failed []string
+			for i, result := range results {
+				if result.Err != nil {
+					failed = append(failed, result.RecordSet.Id)
+					if result.RecordSet.Id != changes[i].rs.Id {
+						t.Errorf("expected failed result %d to hold the requested recordset, got %+v", i, result.RecordSet)
+					}
+					continue
+				}
+				if result.RecordSet.Name != changes[i].rs.Name || result.RecordSet.Status == "" {
+					t.Errorf("expected result %d to hold the returned recordset, got %+v", i, result.RecordSet)
+				}
+			}
+			if fmt.Sprint(failed) != fmt.Sprint(tt.failed) {
+				t.Errorf("expected %v to fail, got %v", tt.failed, failed)
+			}
+			if updates != tt.updates || creates != 1 {
+				t.Errorf("expected %d single updates and 1 creation, got %d and %d", tt.updates, updates, creates)
+			}
+		})
+	}
+}
+
+func TestBatchError(t *testing.T) {
+	errFailed := errors.New("failed")
+	results := []BatchResult{
+		{RecordSet: RecordSet{Id: "rs-1", Name: "a.example.com.", Type: "A"}},
+		{RecordSet: RecordSet{Id: "rs-2", Name: "b.example.com.", Type: "TXT"}, Err: errFailed},
+		{RecordSet: RecordSet{Id: "rs-3", Name: "c.example.com.", Type: "A"}, Deleted: true},
+		{RecordSet: RecordSet{Id: "rs-4", Name: "d.example.com.", Type: "MX"}, Err: &APIError{StatusCode: http.StatusNotFound}},
+	}
+
+	if err := batchError(results[:1]); err != nil {
+		t.Errorf("expected no error when every change succeeded, got %v", err)
+	}
+	err := batchError(results)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Results) != 4 {
+		t.Fatalf("expected a *BatchError holding every result, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "2 of 4 recordset changes failed: b.example.com. TXT: failed; d.example.com. MX: ") {
+		t.Errorf("unexpected message %q", err)
+	}
+	if errs := batchErr.Unwrap(); len(errs) != 2 || errs[0] != errFailed {
+		t.Errorf("expected the errors of the failed changes, got %v", errs)
+	}
+	if !errors.Is(err, errFailed) || !IsNotFound(err) {
+		t.Errorf("expected the errors of the failed changes to be matched")
+	}
+}
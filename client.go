@@ -4,79 +4,217 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	neturl "net/url"
 	"strings"
-
-	"github.com/libdns/libdns"
+	"sync"
+	"time"
 )
 
 type Client struct {
 	accessKeyId     string
 	secretAccessKey string
+	securityToken   string
 	region          string
-	singer          *Signer
+	auth            Authenticator
+	zoneCache       *zoneCache
+	zoneType        string
+	vpcId           string
+	lines           bool
+	retryPolicy     RetryPolicy
+	// sleep waits between retries; tests replace it to avoid real delays.
+	sleep       func(ctx context.Context, delay time.Duration) bool
+	endpoint    string
+	httpClient  *http.Client
+	userAgent   string
+	iamEndpoint string
+	projectId   string
+	projectName string
+	projectMu   sync.Mutex
+	// err records an invalid configuration, returned by every request.
+	err error
+}
+
+// defaultRegion is used when no region is configured.
+const defaultRegion = "cn-south-1"
+
+// DefaultUserAgent is sent with every request unless overridden.
+const DefaultUserAgent = "libdns-huaweicloud"
+
+// ClientOption configures optional behaviour of a Client.
+type ClientOption func(*Client)
+
+// WithZoneCacheTTL sets how long resolved zone IDs are cached. A negative
+// value disables caching of found zones.
+func WithZoneCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		if ttl != 0 {
+			c.zoneCache.ttl = ttl
+		}
+	}
+}
+
+// WithZoneNegativeCacheTTL sets how long "zone not found" results are
+// cached. A negative value disables negative caching.
+func WithZoneNegativeCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		if ttl != 0 {
+			c.zoneCache.negativeTTL = ttl
+		}
+	}
+}
+
+// WithEndpoint overrides the DNS API endpoint, which defaults to
+// "https://dns.<region>.myhuaweicloud.com". The endpoint must not include
+// the API version. This is useful for Huawei Cloud Stack, dedicated
+// regions, or tests against a local server.
+func WithEndpoint(endpoint string) ClientOption {
+	return func(c *Client) {
+		if endpoint != "" {
+			c.endpoint = endpoint
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests, for example
+// to route through a proxy or trust a custom CA.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithSecurityToken sets the security token of temporary AK/SK credentials.
+func WithSecurityToken(securityToken string) ClientOption {
+	return func(c *Client) {
+		c.securityToken = securityToken
+	}
+}
+
+// WithCredentialsProvider makes the client obtain its credentials from the
+// provider instead of the static AK/SK passed to NewClient. Temporary
+// credentials are refreshed automatically before they expire.
+func WithCredentialsProvider(provider CredentialsProvider) ClientOption {
+	return func(c *Client) {
+		if provider != nil {
+			c.auth = SignerAuthenticator{Credentials: newCachedCredentials(provider)}
+		}
+	}
+}
+
+// WithAuthenticator replaces AK/SK signing with another way of
+// authenticating requests, such as a TokenAuthenticator.
+func WithAuthenticator(auth Authenticator) ClientOption {
+	return func(c *Client) {
+		if auth != nil {
+			c.auth = auth
+		}
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) {
+		if userAgent != "" {
+			c.userAgent = userAgent
+		}
+	}
 }
 
 // NewClient creates a new Huawei Cloud DNS client.
-func NewClient(accessKeyId, secretAccessKey, region string) *Client {
+func NewClient(accessKeyId, secretAccessKey, region string, opts ...ClientOption) *Client {
 	if region == "" {
-		region = "cn-south-1"
+		region = defaultRegion
 	}
 
 	client := &Client{
 		accessKeyId:     accessKeyId,
 		secretAccessKey: secretAccessKey,
 		region:          region,
-		singer: &Signer{
-			Key:    accessKeyId,
-			Secret: secretAccessKey,
-		},
+		zoneCache:       newZoneCache(),
+		retryPolicy:     DefaultRetryPolicy(),
+		sleep:           sleep,
+		endpoint:        "https://dns." + region + ".myhuaweicloud.com",
+		httpClient:      http.DefaultClient,
+		userAgent:       DefaultUserAgent,
+		iamEndpoint:     defaultIAMEndpoint(region),
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	if client.auth == nil {
+		client.auth = SignerAuthenticator{Credentials: StaticCredentials{
+			AccessKeyId:     client.accessKeyId,
+			SecretAccessKey: client.secretAccessKey,
+			SecurityToken:   client.securityToken,
+		}}
+	}
+	for _, endpoint := range []string{client.endpoint, client.iamEndpoint} {
+		if _, err := parseEndpoint(endpoint); err != nil {
+			client.err = err
+		}
 	}
 
 	return client
 }
 
+// GetRecords returns every recordset of the zone, following pagination
+// until all pages have been fetched.
 func (c *Client) GetRecords(ctx context.Context, zone string) ([]RecordSet, error) {
-	zoneId, err := c.getZoneId(ctx, zone)
-	if err != nil {
-		return nil, err
-	}
+	return c.IterRecords(ctx, zone).All()
+}
 
-	url := c.getBaseURL()
-	url = url.JoinPath("zones", zoneId, "recordsets")
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+// IterRecords returns an iterator over the recordsets of the zone. Pages are
+// fetched lazily as the iterator advances.
+func (c *Client) IterRecords(ctx context.Context, zone string) *Iterator[RecordSet] {
+	return c.iterRecords(ctx, zone, nil)
+}
 
-	resp := new(ListRecordsResponse)
-	if err = c.doAPIRequest(req, resp); err != nil {
-		return nil, err
-	}
+func (c *Client) iterRecords(ctx context.Context, zone string, query neturl.Values) *Iterator[RecordSet] {
+	return newIterator(ctx, query, func(ctx context.Context, query neturl.Values) ([]RecordSet, *Links, *Metadata, error) {
+		resp := new(ListRecordsResponse)
+		err := c.withZoneId(ctx, zone, func(zoneId string) error {
+			url := c.getRecordsURL()
+			url = url.JoinPath("zones", zoneId, "recordsets")
+			url.RawQuery = query.Encode()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+			if err != nil {
+				return err
+			}
+
+			return c.doAPIRequest(req, resp)
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
 
-	return resp.RecordSets, nil
+		return resp.RecordSets, resp.Links, resp.Metadata, nil
+	})
 }
 
 func (c *Client) AppendRecord(ctx context.Context, zone string, record RecordSet) (*RecordSet, error) {
-	zoneId, err := c.getZoneId(ctx, zone)
-	if err != nil {
-		return nil, err
-	}
-
 	body, err := json.Marshal(record)
 	if err != nil {
 		return nil, err
 	}
 
-	url := c.getBaseURL()
-	url = url.JoinPath("zones", zoneId, "recordsets")
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url.String(), bytes.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-
 	resp := new(RecordSet)
-	if err = c.doAPIRequest(req, resp); err != nil {
+	err = c.withZoneId(ctx, zone, func(zoneId string) error {
+		url := c.getRecordsURL()
+		url = url.JoinPath("zones", zoneId, "recordsets")
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url.String(), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		return c.doAPIRequest(req, resp)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -84,140 +222,266 @@ func (c *Client) AppendRecord(ctx context.Context, zone string, record RecordSet
 }
 
 func (c *Client) UpdateRecord(ctx context.Context, zone string, record RecordSet) (*RecordSet, error) {
-	zoneId, err := c.getZoneId(ctx, zone)
-	if err != nil {
-		return nil, err
-	}
-
 	body, err := json.Marshal(record)
 	if err != nil {
 		return nil, err
 	}
 
-	url := c.getBaseURL()
-	url = url.JoinPath("zones", zoneId, "recordsets", record.Id)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url.String(), bytes.NewReader(body))
+	resp := new(RecordSet)
+	err = c.withZoneId(ctx, zone, func(zoneId string) error {
+		url := c.getRecordsURL()
+		url = url.JoinPath("zones", zoneId, "recordsets", record.Id)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url.String(), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		return c.doAPIRequest(req, resp)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return resp, nil
+}
+
+func (c *Client) DeleteRecord(ctx context.Context, zone string, recordId string) (*RecordSet, error) {
 	resp := new(RecordSet)
-	if err = c.doAPIRequest(req, resp); err != nil {
+	err := c.withZoneId(ctx, zone, func(zoneId string) error {
+		url := c.getRecordsURL()
+		url = url.JoinPath("zones", zoneId, "recordsets", recordId)
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url.String(), nil)
+		if err != nil {
+			return err
+		}
+
+		return c.doAPIRequest(req, resp)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return resp, nil
 }
 
-func (c *Client) DeleteRecord(ctx context.Context, zone string, recordId string) (*RecordSet, error) {
-	zoneId, err := c.getZoneId(ctx, zone)
+// FindRecordSets returns the recordsets named recName and, unless recType
+// is empty, of type recType.
+func (c *Client) FindRecordSets(ctx context.Context, zone, recName, recType string) ([]RecordSet, error) {
+	return c.FindRecordSetsByLine(ctx, zone, recName, recType, "")
+}
+
+// FindRecordSetsByLine is like FindRecordSets, and additionally only returns
+// the recordsets of the given resolution line unless line is empty.
+func (c *Client) FindRecordSetsByLine(ctx context.Context, zone, recName, recType, line string) ([]RecordSet, error) {
+	name := fqdn(recName, zone)
+
+	query := neturl.Values{}
+	query.Set("search_mode", "equal")
+	query.Set("name", name)
+	if recType != "" {
+		query.Set("type", recType)
+	}
+	if line != "" && c.lines {
+		query.Set("line_id", line)
+	}
+	recordSets, err := c.iterRecords(ctx, zone, query).All()
 	if err != nil {
 		return nil, err
 	}
 
-	url := c.getBaseURL()
-	url = url.JoinPath("zones", zoneId, "recordsets", recordId)
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url.String(), nil)
-	if err != nil {
-		return nil, err
+	var results []RecordSet
+	for _, rs := range recordSets {
+		if !strings.EqualFold(fqdn(rs.Name, zone), name) {
+			continue
+		}
+		if recType != "" && !strings.EqualFold(rs.Type, recType) {
+			continue
+		}
+		if line != "" && !sameLine(rs.Line, line) {
+			continue
+		}
+		results = append(results, rs)
 	}
+	return results, nil
+}
 
-	resp := new(RecordSet)
-	if err = c.doAPIRequest(req, resp); err != nil {
+// getRecordSet returns the recordset holding the (recName, recType) RRset
+// of the resolution line, or nil if there is none.
+func (c *Client) getRecordSet(ctx context.Context, zone, recName, recType, line string) (*RecordSet, error) {
+	recordSets, err := c.FindRecordSetsByLine(ctx, zone, recName, recType, lineOrDefault(line))
+	if err != nil {
 		return nil, err
 	}
 
-	return resp, nil
+	switch len(recordSets) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &recordSets[0], nil
+	default:
+		return nil, fmt.Errorf("returned more than one %s record for %q, expected one, actual %d", recType, recName, len(recordSets))
+	}
 }
 
 func (c *Client) GetRecordId(ctx context.Context, zone, recName, recType string, recVal ...string) (string, error) {
-	zoneId, err := c.getZoneId(ctx, zone)
+	recordSets, err := c.FindRecordSets(ctx, zone, recName, recType)
 	if err != nil {
 		return "", err
 	}
 
-	url := c.getBaseURL()
-	url = url.JoinPath("zones", zoneId, "recordsets")
-	query := url.Query()
-	query.Set("search_mode", "equal")
-	query.Set("type", recType)
-	query.Set("name", libdns.AbsoluteName(recName, zone))
-	url.RawQuery = query.Encode()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
-
-	resp := new(ListRecordsResponse)
-	if err = c.doAPIRequest(req, resp); err != nil {
-		return "", err
-	}
-
-	if len(resp.RecordSets) == 0 {
-		return "", fmt.Errorf("record %q not found", recName)
+	if len(recordSets) == 0 {
+		return "", &NotFoundError{Resource: "record", Name: recName}
 	}
-	if len(resp.RecordSets) != 1 {
-		return "", fmt.Errorf("returned more than one record for %q, expected one, actual %d", recName, len(resp.RecordSets))
+	if len(recordSets) != 1 {
+		return "", fmt.Errorf("returned more than one record for %q, expected one, actual %d", recName, len(recordSets))
 	}
 
 	if len(recVal) > 0 && recVal[0] != "" {
-		rec, err := resp.RecordSets[0].libdnsRecord(zone)
+		rec, err := recordSets[0].libdnsRecord(zone)
 		if err != nil {
 			return "", err
 		}
 		for _, r := range rec {
 			rr := r.RR()
 			if rr.Data == recVal[0] {
-				return resp.RecordSets[0].Id, nil
+				return recordSets[0].Id, nil
 			}
 		}
+		return "", &NotFoundError{Resource: "record", Name: recName}
 	}
 
-	return resp.RecordSets[0].Id, nil
+	return recordSets[0].Id, nil
 }
 
-func (c *Client) getZoneId(ctx context.Context, zone string) (string, error) {
-	zone = strings.TrimSuffix(zone, ".")
+func (c *Client) getBaseURL() *neturl.URL {
+	return c.getVersionURL("v2")
+}
 
-	url := c.getBaseURL()
-	url = url.JoinPath("zones")
-	query := url.Query()
-	query.Set("name", strings.TrimSuffix(zone, "."))
-	query.Set("search_mode", "equal")
-	url.RawQuery = query.Encode()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+// getRecordsURL returns the base URL of the API version used for
+// recordsets, which is v2.1 with resolution lines enabled.
+func (c *Client) getRecordsURL() *neturl.URL {
+	if c.lines {
+		return c.getVersionURL("v2.1")
+	}
+	return c.getBaseURL()
+}
 
-	resp := new(ListZonesResponse)
-	if err = c.doAPIRequest(req, resp); err != nil {
-		return "", err
+func (c *Client) getVersionURL(version string) *neturl.URL {
+	baseURL, err := parseEndpoint(c.endpoint)
+	if err != nil {
+		return &neturl.URL{}
 	}
+	return baseURL.JoinPath(version)
+}
 
-	if len(resp.Zones) == 0 {
-		return "", fmt.Errorf("zone %q not found", zone)
+// parseEndpoint parses an API endpoint, which must be an absolute HTTP(S)
+// URL without query or fragment.
+func parseEndpoint(endpoint string) (*neturl.URL, error) {
+	u, err := neturl.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %v", endpoint, err)
 	}
-	if len(resp.Zones) != 1 {
-		return "", fmt.Errorf("returned more than one zone for %q, expected one, actual %d", zone, len(resp.Zones))
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("invalid endpoint %q: expected an absolute http(s) URL such as https://dns.cn-south-1.myhuaweicloud.com", endpoint)
 	}
+	return u, nil
+}
 
-	return resp.Zones[0].Id, nil
+// doAPIRequest signs and sends the request, retrying failed attempts
+// according to the retry policy, and decodes the response into result.
+func (c *Client) doAPIRequest(req *http.Request, result any) error {
+	return c.doRequest(req, result, true)
 }
 
-func (c *Client) getBaseURL() *neturl.URL {
-	baseURL, _ := neturl.Parse("https://dns." + c.region + ".myhuaweicloud.com/v2")
-	return baseURL
+// doRequest implements doAPIRequest. The project header is omitted when
+// withProject is false, as for the IAM request resolving the project.
+func (c *Client) doRequest(req *http.Request, result any, withProject bool) error {
+	if c.err != nil {
+		return c.err
+	}
+	if withProject {
+		projectId, err := c.GetProjectId(req.Context())
+		if err != nil {
+			return err
+		}
+		if projectId != "" {
+			req.Header.Set(HeaderXProjectId, projectId)
+		}
+	}
+	if req.Body != nil && req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		req.Body.Close()
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	ctx := req.Context()
+	policy := c.retryPolicy
+	reauthenticated := false
+	for attempt := 1; ; attempt++ {
+		err := c.doAPIRequestOnce(req, result)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.reauthenticate && !reauthenticated {
+			// The token was revoked or expired early. Log in again once,
+			// without counting it as a retry.
+			reauthenticated = true
+			attempt--
+			continue
+		}
+		if err == nil || attempt >= policy.MaxAttempts || !policy.shouldRetry(req.Method, err) {
+			return unwrapTransportError(err)
+		}
+
+		delay := policy.backoff(attempt)
+		if errors.As(err, &apiErr) && apiErr.retryAfter > delay {
+			delay = apiErr.retryAfter
+			if delay > MaxRetryAfter {
+				delay = MaxRetryAfter
+			}
+		}
+		if !c.sleep(ctx, delay) {
+			return unwrapTransportError(err)
+		}
+	}
 }
 
-func (c *Client) doAPIRequest(req *http.Request, result any) error {
-	if err := c.singer.Sign(req); err != nil {
+// doAPIRequestOnce sends a freshly signed copy of the request.
+func (c *Client) doAPIRequestOnce(req *http.Request, result any) error {
+	attempt := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		attempt.Body = body
+	}
+
+	attempt.Header.Set("User-Agent", c.userAgent)
+	if attempt.Body != nil {
+		attempt.Header.Set("Content-Type", "application/json")
+	}
+	if err := c.auth.Authenticate(attempt); err != nil {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient.Do(attempt)
 	if err != nil {
-		return err
+		return &transportError{err: err}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("got error status: HTTP %d: %+v", resp.StatusCode, string(body))
+		apiErr := newAPIError(resp, body)
+		apiErr.retryAfter = retryAfter(resp.Header, time.Now())
+		if auth, ok := c.auth.(*TokenAuthenticator); ok && resp.StatusCode == http.StatusUnauthorized {
+			apiErr.reauthenticate = auth.invalidateToken(attempt.Header.Get(HeaderXAuthToken))
+		}
+		return apiErr
 	}
 
 	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
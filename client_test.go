@@ -0,0 +1,207 @@
+package huaweicloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingAuthenticator counts the requests it authenticates.
+type countingAuthenticator struct {
+	Authenticator
+	calls int
+}
+
+func (a *countingAuthenticator) Authenticate(req *http.Request) error {
+	a.calls++
+	return a.Authenticator.Authenticate(req)
+}
+
+func TestClientRetry(t *testing.T) {
+	var mu sync.Mutex
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if got := r.Header.Get("User-Agent"); got != "test-agent" {
+			t.Errorf("expected custom User-Agent, got %q", got)
+		}
+		if !strings.HasPrefix(r.Header.Get(HeaderXAuthorization), SignAlgorithm) {
+			t.Errorf("expected signed request, got %q", r.Header.Get(HeaderXAuthorization))
+		}
+		attempts++
+
+		switch attempts {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error_code":"APIGW.0308","error_msg":"throttled"}`))
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_ = json.NewEncoder(w).Encode(ListZonesResponse{Zones: []Zone{{Id: "zone-id", Name: "example.com."}}})
+		}
+	}))
+	defer server.Close()
+
+	auth := &countingAuthenticator{Authenticator: SignerAuthenticator{Credentials: StaticCredentials{AccessKeyId: "ak", SecretAccessKey: "sk"}}}
+	client := NewClient("ak", "sk", "", WithEndpoint(server.URL), WithUserAgent("test-agent"),
+		WithHTTPClient(server.Client()), WithAuthenticator(auth), WithRetryPolicy(RetryPolicy{
+			MaxAttempts:   3,
+			BaseDelay:     time.Millisecond,
+			MaxDelay:      time.Second,
+			RetryStatuses: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+		}))
+	var delays []time.Duration
+	client.sleep = func(ctx context.Context, delay time.Duration) bool {
+		delays = append(delays, delay)
+		return true
+	}
+
+	zones, err := client.ListZones(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(zones) != 1 || zones[0].Id != "zone-id" {
+		t.Errorf("unexpected zones %+v", zones)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+	if auth.calls != 3 {
+		t.Errorf("expected every attempt to be signed again, got %d signatures", auth.calls)
+	}
+	if len(delays) != 2 || delays[0] != time.Second || delays[1] != 2*time.Millisecond {
+		t.Errorf("expected to wait for Retry-After and then back off, got %v", delays)
+	}
+}
+
+func TestClientRetryAfterIsCapped(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ListZonesResponse{})
+	}))
+	defer server.Close()
+
+	client := NewClient("ak", "sk", "", WithEndpoint(server.URL))
+	var delays []time.Duration
+	client.sleep = func(ctx context.Context, delay time.Duration) bool {
+		delays = append(delays, delay)
+		return true
+	}
+	if _, err := client.ListZones(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(delays) != 1 || delays[0] != MaxRetryAfter {
+		t.Errorf("expected to wait %v, got %v", MaxRetryAfter, delays)
+	}
+}
+
+func TestClientRetryExhausted(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set(HeaderXRequestId, "req-1")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"code":"DNS.0001","message":"internal error"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("ak", "sk", "", WithEndpoint(server.URL), WithRetryPolicy(RetryPolicy{
+		MaxAttempts:   2,
+		BaseDelay:     time.Millisecond,
+		RetryStatuses: []int{http.StatusInternalServerError},
+	}))
+
+	_, err := client.AppendRecord(context.Background(), "example.com.", RecordSet{Name: "www.example.com.", Type: "A"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if attempts != 2 {
+		t.Errorf("expected the zone lookup to be attempted twice and the POST never, got %d attempts", attempts)
+	}
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.Code != "DNS.0001" || apiErr.RequestId != "req-1" {
+		t.Errorf("unexpected error %#v", err)
+	}
+}
+
+func TestClientInvalidEndpoint(t *testing.T) {
+	client := NewClient("ak", "sk", "", WithEndpoint("dns.example.com"))
+	if _, err := client.ListZones(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid endpoint") {
+		t.Errorf("expected invalid endpoint error, got %v", err)
+	}
+}
+
+type failingCredentials struct {
+	calls int
+}
+
+func (c *failingCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	c.calls++
+	return Credentials{}, errors.New("boom")
+}
+
+func TestClientDoesNotRetryLocalErrors(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		_, _ = w.Write([]byte(`not json`))
+	}))
+	defer server.Close()
+
+	creds := &failingCredentials{}
+	client := NewClient("", "", "", WithEndpoint(server.URL), WithCredentialsProvider(creds))
+	if _, err := client.ListZones(context.Background()); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected the credentials error, got %v", err)
+	}
+	if creds.calls != 1 || attempts != 0 {
+		t.Errorf("expected a single credentials lookup and no request, got %d lookups and %d requests", creds.calls, attempts)
+	}
+
+	client = NewClient("ak", "sk", "", WithEndpoint(server.URL))
+	if _, err := client.ListZones(context.Background()); err == nil {
+		t.Fatal("expected a decoding error")
+	}
+	if attempts != 1 {
+		t.Errorf("expected an undecodable response not to be retried, got %d attempts", attempts)
+	}
+}
+
+func TestClientRetriesNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	endpoint := server.URL
+	server.Close()
+
+	var attempts int
+	client := NewClient("ak", "sk", "", WithEndpoint(endpoint), WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+		WithHTTPClient(&http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			attempts++
+			return http.DefaultTransport.RoundTrip(r)
+		})}))
+	_, err := client.ListZones(context.Background())
+	var tErr *transportError
+	if err == nil || errors.As(err, &tErr) {
+		t.Fatalf("expected the plain network error, got %#v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
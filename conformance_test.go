@@ -0,0 +1,31 @@
+package huaweicloud_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/libdns/huaweicloud"
+	"github.com/libdns/huaweicloud/huaweicloudtest"
+)
+
+func TestConformance(t *testing.T) {
+	server := huaweicloudtest.NewServer(map[string]string{"ak": "sk"})
+	defer server.Close()
+	server.AddZone(huaweicloud.Zone{Name: "example.com."})
+	server.AddRecordSet("example.com.", huaweicloud.RecordSet{Name: "www.example.com.", Type: "TXT", Records: []string{`"keep"`}})
+
+	huaweicloudtest.RunConformance(t, &huaweicloud.Provider{
+		AccessKeyId:     "ak",
+		SecretAccessKey: "sk",
+		Endpoint:        server.URL,
+	}, "example.com")
+
+	// The suite cleans up after itself and leaves other records alone.
+	var left []string
+	for _, rs := range server.RecordSets("example.com.") {
+		left = append(left, fmt.Sprintf("%s %s %v", rs.Name, rs.Type, rs.Records))
+	}
+	if expected := []string{`www.example.com. TXT ["keep"]`}; fmt.Sprint(left) != fmt.Sprint(expected) {
+		t.Errorf("expected %v to be left in the zone, got %v", expected, left)
+	}
+}
@@ -0,0 +1,278 @@
+package huaweicloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvAccessKeyId, EnvSecretAccessKey and EnvSecurityToken hold the
+	// credentials read by EnvCredentials.
+	EnvAccessKeyId     = "HUAWEICLOUD_SDK_AK"
+	EnvSecretAccessKey = "HUAWEICLOUD_SDK_SK"
+	EnvSecurityToken   = "HUAWEICLOUD_SDK_SECURITY_TOKEN"
+	// EnvRegion holds the region used when none is configured.
+	EnvRegion = "HUAWEICLOUD_REGION"
+
+	// DefaultECSMetadataEndpoint is the ECS metadata service address.
+	DefaultECSMetadataEndpoint = "http://169.254.169.254"
+
+	// credentialsRefreshWindow is how long before expiry temporary
+	// credentials are refreshed.
+	credentialsRefreshWindow = 5 * time.Minute
+)
+
+// ErrNoCredentials is returned by a CredentialsProvider that has no
+// credentials to offer, so that the next provider of a chain is tried.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Credentials are AK/SK credentials. Temporary credentials also carry a
+// security token and an expiry time.
+type Credentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SecurityToken   string
+	// Expires is zero for long-lived credentials.
+	Expires time.Time
+}
+
+// CredentialsProvider supplies the credentials used to sign requests.
+type CredentialsProvider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// StaticCredentials provides fixed credentials.
+type StaticCredentials Credentials
+
+func (s StaticCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	if s.AccessKeyId == "" || s.SecretAccessKey == "" {
+		return Credentials{}, fmt.Errorf("static: %w", ErrNoCredentials)
+	}
+	return Credentials(s), nil
+}
+
+// EnvCredentials reads credentials from the HUAWEICLOUD_SDK_AK,
+// HUAWEICLOUD_SDK_SK and HUAWEICLOUD_SDK_SECURITY_TOKEN environment variables.
+type EnvCredentials struct{}
+
+func (EnvCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	creds := Credentials{
+		AccessKeyId:     os.Getenv(EnvAccessKeyId),
+		SecretAccessKey: os.Getenv(EnvSecretAccessKey),
+		SecurityToken:   os.Getenv(EnvSecurityToken),
+	}
+	if creds.AccessKeyId == "" || creds.SecretAccessKey == "" {
+		return Credentials{}, fmt.Errorf("environment: %s and %s not set: %w", EnvAccessKeyId, EnvSecretAccessKey, ErrNoCredentials)
+	}
+	return creds, nil
+}
+
+// FileCredentials reads credentials from a profile of the shared hcloud
+// (KooCLI) configuration file. Only profiles storing plain AK/SK are
+// supported.
+type FileCredentials struct {
+	// Path defaults to ~/.hcloud/config.json.
+	Path string
+	// Profile defaults to the file's current profile.
+	Profile string
+}
+
+type hcloudConfig struct {
+	Current  string          `json:"current"`
+	Profiles []hcloudProfile `json:"profiles"`
+}
+
+type hcloudProfile struct {
+	Name            string `json:"name"`
+	AccessKeyId     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	SecurityToken   string `json:"securityToken"`
+}
+
+func (f FileCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	path := f.Path
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Credentials{}, fmt.Errorf("config file: %v: %w", err, ErrNoCredentials)
+		}
+		path = filepath.Join(home, ".hcloud", "config.json")
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Credentials{}, fmt.Errorf("config file: %s does not exist: %w", path, ErrNoCredentials)
+	}
+	if err != nil {
+		return Credentials{}, fmt.Errorf("config file: %v", err)
+	}
+
+	var config hcloudConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Credentials{}, fmt.Errorf("config file: parsing %s: %v", path, err)
+	}
+
+	name := f.Profile
+	if name == "" {
+		name = config.Current
+	}
+	if name == "" {
+		name = "default"
+	}
+	for _, profile := range config.Profiles {
+		if profile.Name != name {
+			continue
+		}
+		if profile.AccessKeyId == "" || profile.SecretAccessKey == "" {
+			return Credentials{}, fmt.Errorf("config file: profile %q has no AK/SK: %w", name, ErrNoCredentials)
+		}
+		return Credentials{
+			AccessKeyId:     profile.AccessKeyId,
+			SecretAccessKey: profile.SecretAccessKey,
+			SecurityToken:   profile.SecurityToken,
+		}, nil
+	}
+	return Credentials{}, fmt.Errorf("config file: profile %q not found in %s: %w", name, path, ErrNoCredentials)
+}
+
+// ECSMetadataCredentials fetches the temporary credentials of the agency
+// bound to the ECS instance from the metadata service.
+type ECSMetadataCredentials struct {
+	// Endpoint defaults to DefaultECSMetadataEndpoint.
+	Endpoint string
+	// HTTPClient defaults to a client with a short timeout, so that the
+	// lookup fails fast outside of ECS.
+	HTTPClient *http.Client
+}
+
+type securityKeyResponse struct {
+	Credential struct {
+		Access        string `json:"access"`
+		Secret        string `json:"secret"`
+		SecurityToken string `json:"securitytoken"`
+		ExpiresAt     string `json:"expires_at"`
+	} `json:"credential"`
+}
+
+func (e ECSMetadataCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	endpoint := e.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultECSMetadataEndpoint
+	}
+	httpClient := e.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 2 * time.Second}
+	}
+
+	url := strings.TrimSuffix(endpoint, "/") + "/openstack/latest/securitykey"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("ecs metadata: %v", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("ecs metadata: %v: %w", err, ErrNoCredentials)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Credentials{}, fmt.Errorf("ecs metadata: no agency bound to the instance: %w", ErrNoCredentials)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Credentials{}, fmt.Errorf("ecs metadata: got error status: HTTP %d", resp.StatusCode)
+	}
+
+	var key securityKeyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&key); err != nil {
+		return Credentials{}, fmt.Errorf("ecs metadata: %v", err)
+	}
+	creds := Credentials{
+		AccessKeyId:     key.Credential.Access,
+		SecretAccessKey: key.Credential.Secret,
+		SecurityToken:   key.Credential.SecurityToken,
+	}
+	if creds.AccessKeyId == "" || creds.SecretAccessKey == "" {
+		return Credentials{}, fmt.Errorf("ecs metadata: response has no credentials")
+	}
+	if key.Credential.ExpiresAt != "" {
+		creds.Expires, err = time.Parse(time.RFC3339Nano, key.Credential.ExpiresAt)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("ecs metadata: invalid expires_at %q: %v", key.Credential.ExpiresAt, err)
+		}
+	}
+	return creds, nil
+}
+
+// ChainCredentials tries each provider in order and returns the first
+// credentials found. Providers reporting ErrNoCredentials are skipped; any
+// other error stops the chain.
+type ChainCredentials []CredentialsProvider
+
+func (c ChainCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	var reasons []string
+	for _, provider := range c {
+		creds, err := provider.Retrieve(ctx)
+		if err == nil {
+			return creds, nil
+		}
+		if !errors.Is(err, ErrNoCredentials) {
+			return Credentials{}, err
+		}
+		reasons = append(reasons, err.Error())
+	}
+	return Credentials{}, fmt.Errorf("huaweicloud: %w found (%s)", ErrNoCredentials, strings.Join(reasons, "; "))
+}
+
+// DefaultCredentialsChain returns the chain used by Provider: the static
+// credentials if set, then environment variables, then the hcloud
+// configuration file, then the ECS metadata service.
+func DefaultCredentialsChain(static StaticCredentials) CredentialsProvider {
+	return ChainCredentials{
+		static,
+		EnvCredentials{},
+		FileCredentials{},
+		ECSMetadataCredentials{},
+	}
+}
+
+// cachedCredentials caches the credentials of a provider, refreshing
+// temporary credentials shortly before they expire.
+type cachedCredentials struct {
+	provider CredentialsProvider
+	now      func() time.Time
+
+	mu    sync.Mutex
+	creds *Credentials
+}
+
+func newCachedCredentials(provider CredentialsProvider) *cachedCredentials {
+	return &cachedCredentials{provider: provider, now: time.Now}
+}
+
+func (c *cachedCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.creds != nil && (c.creds.Expires.IsZero() || c.now().Before(c.creds.Expires.Add(-credentialsRefreshWindow))) {
+		return *c.creds, nil
+	}
+
+	creds, err := c.provider.Retrieve(ctx)
+	if err != nil {
+		// Keep using credentials that are about to expire but still valid.
+		if c.creds != nil && c.now().Before(c.creds.Expires) {
+			return *c.creds, nil
+		}
+		return Credentials{}, err
+	}
+	c.creds = &creds
+	return creds, nil
+}
@@ -0,0 +1,148 @@
+package huaweicloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCredentialsChain(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	err := os.WriteFile(configPath, []byte(`{
+		"current": "work",
+		"profiles": [
+			{"name": "default", "mode": "AKSK", "accessKeyId": "default-ak", "secretAccessKey": "default-sk"},
+			{"name": "work", "mode": "AKSK", "accessKeyId": "file-ak", "secretAccessKey": "file-sk"}
+		]
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openstack/latest/securitykey" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"credential":{"access":"ecs-ak","secret":"ecs-sk","securitytoken":"ecs-token","expires_at":"2030-01-01T00:00:00.000000Z"}}`)
+	}))
+	defer metadata.Close()
+
+	chain := func(static StaticCredentials, configPath string) ChainCredentials {
+		return ChainCredentials{
+			static,
+			EnvCredentials{},
+			FileCredentials{Path: configPath},
+			ECSMetadataCredentials{Endpoint: metadata.URL},
+		}
+	}
+	missing := filepath.Join(dir, "missing.json")
+
+	tests := []struct {
+		name       string
+		static     StaticCredentials
+		env        bool
+		configPath string
+		expected   Credentials
+	}{
+		{
+			name:       "static",
+			static:     StaticCredentials{AccessKeyId: "static-ak", SecretAccessKey: "static-sk"},
+			env:        true,
+			configPath: configPath,
+			expected:   Credentials{AccessKeyId: "static-ak", SecretAccessKey: "static-sk"},
+		},
+		{
+			name:       "environment",
+			env:        true,
+			configPath: configPath,
+			expected:   Credentials{AccessKeyId: "env-ak", SecretAccessKey: "env-sk", SecurityToken: "env-token"},
+		},
+		{
+			name:       "config file",
+			configPath: configPath,
+			expected:   Credentials{AccessKeyId: "file-ak", SecretAccessKey: "file-sk"},
+		},
+		{
+			name:       "ecs metadata",
+			configPath: missing,
+			expected: Credentials{
+				AccessKeyId:     "ecs-ak",
+				SecretAccessKey: "ecs-sk",
+				SecurityToken:   "ecs-token",
+				Expires:         time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env {
+				t.Setenv(EnvAccessKeyId, "env-ak")
+				t.Setenv(EnvSecretAccessKey, "env-sk")
+				t.Setenv(EnvSecurityToken, "env-token")
+			} else {
+				t.Setenv(EnvAccessKeyId, "")
+				t.Setenv(EnvSecretAccessKey, "")
+			}
+
+			creds, err := chain(tt.static, tt.configPath).Retrieve(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if creds != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, creds)
+			}
+		})
+	}
+
+	t.Run("none", func(t *testing.T) {
+		t.Setenv(EnvAccessKeyId, "")
+		t.Setenv(EnvSecretAccessKey, "")
+		_, err := ChainCredentials{EnvCredentials{}, FileCredentials{Path: missing}}.Retrieve(context.Background())
+		if !errors.Is(err, ErrNoCredentials) {
+			t.Errorf("expected ErrNoCredentials, got %v", err)
+		}
+	})
+}
+
+type countingCredentials struct {
+	calls   int
+	expires time.Time
+}
+
+func (c *countingCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	c.calls++
+	return Credentials{AccessKeyId: "ak", SecretAccessKey: "sk", Expires: c.expires}, nil
+}
+
+func TestCachedCredentials(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	provider := &countingCredentials{expires: now.Add(time.Hour)}
+	cached := newCachedCredentials(provider)
+	cached.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := cached.Retrieve(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if provider.calls != 1 {
+		t.Errorf("expected credentials to be cached, got %d calls", provider.calls)
+	}
+
+	now = now.Add(time.Hour - credentialsRefreshWindow)
+	if _, err := cached.Retrieve(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected credentials to be refreshed before expiry, got %d calls", provider.calls)
+	}
+}
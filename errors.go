@@ -0,0 +1,186 @@
+package huaweicloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderXRequestId carries the ID Huawei Cloud assigns to every request.
+	HeaderXRequestId = "X-Request-Id"
+)
+
+// APIError is returned for error responses of the Huawei Cloud API.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Code is the Huawei Cloud error code, e.g. "DNS.0312" or "APIGW.0301".
+	Code string
+	// Message is the human readable error message.
+	Message string
+	// RequestId is the value of the X-Request-Id response header.
+	RequestId string
+	// Method and URL identify the failed request.
+	Method string
+	URL    string
+	// Body is the raw response body, kept for errors that are not JSON.
+	Body string
+
+	// retryAfter is the delay requested by the Retry-After header.
+	retryAfter time.Duration
+	// reauthenticate is set when the request was rejected with an expired
+	// token that has since been dropped, so that it can be sent again.
+	reauthenticate bool
+}
+
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s: HTTP %d", e.Method, e.URL, e.StatusCode)
+	switch {
+	case e.Code != "" || e.Message != "":
+		fmt.Fprintf(&sb, ": %s: %s", e.Code, e.Message)
+	case e.Body != "":
+		fmt.Fprintf(&sb, ": %s", e.Body)
+	}
+	if e.RequestId != "" {
+		fmt.Fprintf(&sb, " (request id %s)", e.RequestId)
+	}
+	return sb.String()
+}
+
+// newAPIError builds an APIError from an error response and its body.
+// The DNS service reports "code"/"message", while the API gateway and IAM
+// report "error_code"/"error_msg" or a nested "error" object.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestId:  resp.Header.Get(HeaderXRequestId),
+		Body:       string(body),
+	}
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.URL = resp.Request.URL.String()
+	}
+
+	var payload struct {
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		ErrorCode string `json:"error_code"`
+		ErrorMsg  string `json:"error_msg"`
+		RequestId string `json:"request_id"`
+		Error     *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return apiErr
+	}
+	switch {
+	case payload.ErrorCode != "" || payload.ErrorMsg != "":
+		apiErr.Code, apiErr.Message = payload.ErrorCode, payload.ErrorMsg
+	case payload.Code != "" || payload.Message != "":
+		apiErr.Code, apiErr.Message = payload.Code, payload.Message
+	case payload.Error != nil:
+		apiErr.Code, apiErr.Message = payload.Error.Code, payload.Error.Message
+	}
+	if apiErr.RequestId == "" {
+		apiErr.RequestId = payload.RequestId
+	}
+	return apiErr
+}
+
+// NotFoundError is returned when a zone or record does not exist.
+type NotFoundError struct {
+	// Resource is the kind of the missing resource, "zone" or "record".
+	Resource string
+	// Name is the name that was looked up.
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %q not found", e.Resource, e.Name)
+}
+
+// UnsupportedTypeError is returned before any request is made when a record
+// has a type Huawei Cloud DNS does not support.
+type UnsupportedTypeError struct {
+	Type string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported record type %q", e.Type)
+}
+
+// IsNotFound reports whether err means that a zone or record does not
+// exist, either as an HTTP 404 response or a failed lookup.
+func IsNotFound(err error) bool {
+	var nf *NotFoundError
+	if errors.As(err, &nf) {
+		return true
+	}
+	return isStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err means that the resource already exists.
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusConflict || apiErr.Code == "DNS.0312"
+}
+
+// IsThrottled reports whether err means that the request was rate limited.
+func IsThrottled(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.Code == "APIGW.0308"
+}
+
+// IsAuth reports whether err means that the request was not authenticated
+// or not authorized.
+func IsAuth(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+}
+
+// isStatus reports whether err is an API error with the given status code.
+func isStatus(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+// codeZoneNotFound is the error code of requests for a missing zone.
+const codeZoneNotFound = "DNS.0101"
+
+// isZoneGone reports whether err means that the zone with the given ID does
+// not exist: a 404 with the zone error code, or for the zone resource itself.
+// A 404 for a recordset of an existing zone is not.
+func isZoneGone(err error, zoneId string) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		return false
+	}
+	if apiErr.Code == codeZoneNotFound {
+		return true
+	}
+	u, parseErr := neturl.Parse(apiErr.URL)
+	return parseErr == nil && strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), "/zones/"+zoneId)
+}
+
+// isZoneNotFound reports whether err is a failed zone lookup.
+func isZoneNotFound(err error) bool {
+	var nf *NotFoundError
+	return errors.As(err, &nf) && nf.Resource == "zone"
+}
@@ -0,0 +1,74 @@
+package huaweicloud
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		code      string
+		message   string
+		notFound  bool
+		conflict  bool
+		throttled bool
+		auth      bool
+	}{
+		{
+			name:     "dns",
+			status:   http.StatusBadRequest,
+			body:     `{"code":"DNS.0312","message":"Attribute 'name' conflicts with Record Set 'www.example.com.' type 'A'."}`,
+			code:     "DNS.0312",
+			message:  "Attribute 'name' conflicts with Record Set 'www.example.com.' type 'A'.",
+			conflict: true,
+		},
+		{
+			name:    "gateway",
+			status:  http.StatusUnauthorized,
+			body:    `{"error_code":"APIGW.0301","error_msg":"Incorrect IAM authentication information","request_id":"abc"}`,
+			code:    "APIGW.0301",
+			message: "Incorrect IAM authentication information",
+			auth:    true,
+		},
+		{
+			name:      "throttled",
+			status:    http.StatusTooManyRequests,
+			body:      `{"error_code":"APIGW.0308","error_msg":"The throttling threshold has been reached"}`,
+			code:      "APIGW.0308",
+			message:   "The throttling threshold has been reached",
+			throttled: true,
+		},
+		{
+			name:     "not json",
+			status:   http.StatusNotFound,
+			body:     `404 page not found`,
+			notFound: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://dns.cn-south-1.myhuaweicloud.com/v2/zones", nil)
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}, Request: req}
+			resp.Header.Set(HeaderXRequestId, "req-1")
+
+			apiErr := newAPIError(resp, []byte(tt.body))
+			if apiErr.Code != tt.code || apiErr.Message != tt.message {
+				t.Errorf("expected %q/%q, got %q/%q", tt.code, tt.message, apiErr.Code, apiErr.Message)
+			}
+			if apiErr.RequestId != "req-1" || apiErr.Method != http.MethodGet {
+				t.Errorf("unexpected request details %+v", apiErr)
+			}
+
+			err := fmt.Errorf("wrapped: %w", apiErr)
+			if IsNotFound(err) != tt.notFound || IsConflict(err) != tt.conflict || IsThrottled(err) != tt.throttled || IsAuth(err) != tt.auth {
+				t.Errorf("unexpected classification of %v", err)
+			}
+		})
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/libdns/huaweicloud
 
-go 1.18
+go 1.20
 
 require github.com/libdns/libdns v1.1.0
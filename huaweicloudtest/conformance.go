@@ -0,0 +1,290 @@
+package huaweicloudtest
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// Provider is the part of the libdns contract checked by RunConformance.
+type Provider interface {
+	libdns.RecordGetter
+	libdns.RecordAppender
+	libdns.RecordSetter
+	libdns.RecordDeleter
+}
+
+// conformanceTTL is the TTL of the records created by RunConformance.
+const conformanceTTL = 5 * time.Minute
+
+// RunConformance checks that p follows the libdns contract in zone, which
+// must exist and is given without a trailing dot to also cover that form.
+// Every subtest works on names starting with "libdns-test-" and deletes
+// its records when done, so the zone may hold other records and the
+// suite can run against a real account as well as against a Server.
+func RunConformance(t *testing.T, p Provider, zone string) {
+	zone = strings.TrimSuffix(zone, ".")
+	c := &conformance{p: p, zone: zone}
+
+	t.Run("round trip", c.testRoundTrip)
+	t.Run("append is idempotent", c.testAppendIdempotent)
+	t.Run("set replaces rrsets", c.testSetReplaces)
+	t.Run("delete with wildcards", c.testDeleteWildcards)
+	t.Run("absolute names", c.testAbsoluteNames)
+	t.Run("zone with trailing dot", c.testZoneTrailingDot)
+}
+
+type conformance struct {
+	p    Provider
+	zone string
+}
+
+// roundTripRecords returns a record of every type libdns models, named
+// after prefix.
+func roundTripRecords(prefix, zone string) []libdns.Record {
+	target := "target." + zone + "."
+	return []libdns.Record{
+		libdns.Address{Name: prefix, TTL: conformanceTTL, IP: netip.MustParseAddr("192.0.2.1")},
+		libdns.Address{Name: prefix, TTL: conformanceTTL, IP: netip.MustParseAddr("2001:db8::1")},
+		libdns.CNAME{Name: prefix + "-cname", TTL: conformanceTTL, Target: target},
+		libdns.MX{Name: prefix, TTL: conformanceTTL, Preference: 10, Target: target},
+		libdns.TXT{Name: prefix, TTL: conformanceTTL, Text: "v=spf1 -all"},
+		libdns.TXT{Name: prefix, TTL: conformanceTTL, Text: `quotes " and backslashes \`},
+		libdns.TXT{Name: prefix + "-long", TTL: conformanceTTL, Text: "v=DKIM1; k=rsa; p=" + strings.Repeat("MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8A", 16)},
+		libdns.SRV{Service: "sip", Transport: "tcp", Name: prefix, TTL: conformanceTTL, Priority: 10, Weight: 5, Port: 5060, Target: target},
+		libdns.CAA{Name: prefix, TTL: conformanceTTL, Flags: 0, Tag: "issue", Value: "letsencrypt.org"},
+		libdns.NS{Name: prefix + "-ns", TTL: conformanceTTL, Target: target},
+		libdns.ServiceBinding{Scheme: "https", Name: prefix, TTL: conformanceTTL, Priority: 1, Target: ".", Params: libdns.SvcParams{"alpn": {"h2"}}},
+		libdns.ServiceBinding{Scheme: "dns", URLSchemePort: 853, Name: prefix, TTL: conformanceTTL, Priority: 1, Target: target, Params: libdns.SvcParams{"alpn": {"dot"}}},
+	}
+}
+
+func (c *conformance) testRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	prefix := "libdns-test-types"
+	defer c.cleanup(t, prefix)
+
+	records := roundTripRecords(prefix, c.zone)
+	added, err := c.p.AppendRecords(ctx, c.zone, records)
+	if err != nil {
+		t.Fatalf("appending records: %v", err)
+	}
+	c.expect(t, "appended", added, records)
+	c.expectZone(t, prefix, records)
+}
+
+func (c *conformance) testAppendIdempotent(t *testing.T) {
+	ctx := context.Background()
+	prefix := "libdns-test-append"
+	defer c.cleanup(t, prefix)
+
+	first := []libdns.Record{libdns.Address{Name: prefix, TTL: conformanceTTL, IP: netip.MustParseAddr("192.0.2.1")}}
+	if _, err := c.p.AppendRecords(ctx, c.zone, first); err != nil {
+		t.Fatalf("appending records: %v", err)
+	}
+
+	second := []libdns.Record{
+		libdns.Address{Name: prefix, TTL: conformanceTTL, IP: netip.MustParseAddr("192.0.2.1")},
+		libdns.Address{Name: prefix, TTL: conformanceTTL, IP: netip.MustParseAddr("192.0.2.2")},
+	}
+	added, err := c.p.AppendRecords(ctx, c.zone, second)
+	if err != nil {
+		t.Fatalf("appending records again: %v", err)
+	}
+	c.expect(t, "appended", added, second[1:])
+
+	added, err = c.p.AppendRecords(ctx, c.zone, second)
+	if err != nil {
+		t.Fatalf("appending existing records: %v", err)
+	}
+	c.expect(t, "appended", added, nil)
+	c.expectZone(t, prefix, second)
+}
+
+func (c *conformance) testSetReplaces(t *testing.T) {
+	ctx := context.Background()
+	prefix := "libdns-test-set"
+	defer c.cleanup(t, prefix)
+
+	initial := []libdns.Record{
+		libdns.Address{Name: prefix, TTL: conformanceTTL, IP: netip.MustParseAddr("192.0.2.1")},
+		libdns.Address{Name: prefix, TTL: conformanceTTL, IP: netip.MustParseAddr("192.0.2.2")},
+		libdns.TXT{Name: prefix, TTL: conformanceTTL, Text: "kept"},
+	}
+	if _, err := c.p.AppendRecords(ctx, c.zone, initial); err != nil {
+		t.Fatalf("appending records: %v", err)
+	}
+
+	// Setting one A record replaces the whole A rrset of the name, and
+	// leaves the other types and names alone.
+	set := []libdns.Record{
+		libdns.Address{Name: prefix, TTL: 2 * conformanceTTL, IP: netip.MustParseAddr("192.0.2.3")},
+		libdns.Address{Name: prefix + "-new", TTL: conformanceTTL, IP: netip.MustParseAddr("192.0.2.4")},
+	}
+	got, err := c.p.SetRecords(ctx, c.zone, set)
+	if err != nil {
+		t.Fatalf("setting records: %v", err)
+	}
+	c.expect(t, "set", got, set)
+	c.expectZone(t, prefix, append([]libdns.Record{initial[2]}, set...))
+}
+
+func (c *conformance) testDeleteWildcards(t *testing.T) {
+	ctx := context.Background()
+	prefix := "libdns-test-delete"
+	defer c.cleanup(t, prefix)
+
+	initial := []libdns.Record{
+		libdns.Address{Name: prefix, TTL: conformanceTTL, IP: netip.MustParseAddr("192.0.2.1")},
+		libdns.Address{Name: prefix, TTL: conformanceTTL, IP: netip.MustParseAddr("192.0.2.2")},
+		libdns.TXT{Name: prefix, TTL: conformanceTTL, Text: "first"},
+		libdns.TXT{Name: prefix, TTL: conformanceTTL, Text: "second"},
+		libdns.TXT{Name: prefix + "-other", TTL: conformanceTTL, Text: "other"},
+	}
+	if _, err := c.p.AppendRecords(ctx, c.zone, initial); err != nil {
+		t.Fatalf("appending records: %v", err)
+	}
+
+	// An empty type matches every type, an empty value every value and a
+	// zero TTL every TTL. Records that do not exist are ignored.
+	deleted, err := c.p.DeleteRecords(ctx, c.zone, []libdns.Record{
+		libdns.RR{Name: prefix, Type: "TXT", Data: "first"},
+		libdns.RR{Name: prefix, Type: "A", TTL: conformanceTTL},
+		libdns.RR{Name: prefix + "-missing"},
+	})
+	if err != nil {
+		t.Fatalf("deleting records: %v", err)
+	}
+	c.expect(t, "deleted", deleted, initial[:3])
+	c.expectZone(t, prefix, initial[3:])
+
+	deleted, err = c.p.DeleteRecords(ctx, c.zone, []libdns.Record{libdns.RR{Name: prefix + "-other"}})
+	if err != nil {
+		t.Fatalf("deleting records: %v", err)
+	}
+	c.expect(t, "deleted", deleted, initial[4:])
+	c.expectZone(t, prefix, initial[3:4])
+}
+
+func (c *conformance) testAbsoluteNames(t *testing.T) {
+	ctx := context.Background()
+	prefix := "libdns-test-absolute"
+	defer c.cleanup(t, prefix)
+
+	// Names are given fully qualified and returned relative to the zone.
+	absolute := prefix + "." + c.zone + "."
+	added, err := c.p.AppendRecords(ctx, c.zone, []libdns.Record{
+		libdns.Address{Name: absolute, TTL: conformanceTTL, IP: netip.MustParseAddr("192.0.2.1")},
+	})
+	if err != nil {
+		t.Fatalf("appending records: %v", err)
+	}
+	relative := []libdns.Record{libdns.Address{Name: prefix, TTL: conformanceTTL, IP: netip.MustParseAddr("192.0.2.1")}}
+	c.expect(t, "appended", added, relative)
+	c.expectZone(t, prefix, relative)
+
+	deleted, err := c.p.DeleteRecords(ctx, c.zone, []libdns.Record{libdns.RR{Name: absolute, Type: "A"}})
+	if err != nil {
+		t.Fatalf("deleting records: %v", err)
+	}
+	c.expect(t, "deleted", deleted, relative)
+}
+
+func (c *conformance) testZoneTrailingDot(t *testing.T) {
+	ctx := context.Background()
+	prefix := "libdns-test-dot"
+	defer c.cleanup(t, prefix)
+
+	records := []libdns.Record{libdns.TXT{Name: prefix, TTL: conformanceTTL, Text: "dot"}}
+	if _, err := c.p.AppendRecords(ctx, c.zone+".", records); err != nil {
+		t.Fatalf("appending records: %v", err)
+	}
+
+	for _, zone := range []string{c.zone, c.zone + "."} {
+		all, err := c.p.GetRecords(ctx, zone)
+		if err != nil {
+			t.Fatalf("getting records of %s: %v", zone, err)
+		}
+		c.expect(t, "records of "+zone, withPrefix(all, prefix), records)
+	}
+}
+
+// expectZone checks the records of the zone whose names start with prefix.
+func (c *conformance) expectZone(t *testing.T, prefix string, want []libdns.Record) {
+	t.Helper()
+	all, err := c.p.GetRecords(context.Background(), c.zone)
+	if err != nil {
+		t.Fatalf("getting records: %v", err)
+	}
+	c.expect(t, "zone records", withPrefix(all, prefix), want)
+}
+
+// expect compares records in their libdns.RR form, ignoring order, and
+// checks that they are of the same Go types.
+func (c *conformance) expect(t *testing.T, what string, got, want []libdns.Record) {
+	t.Helper()
+	if g, w := formatRecords(got), formatRecords(want); !reflect.DeepEqual(g, w) {
+		t.Errorf("%s: expected %q, got %q", what, w, g)
+	}
+	if g, w := recordTypes(got), recordTypes(want); !reflect.DeepEqual(g, w) {
+		t.Errorf("%s: expected record types %v, got %v", what, w, g)
+	}
+}
+
+// cleanup deletes the records whose names start with prefix.
+func (c *conformance) cleanup(t *testing.T, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+	all, err := c.p.GetRecords(ctx, c.zone)
+	if err != nil {
+		t.Errorf("cleaning up: %v", err)
+		return
+	}
+	if records := withPrefix(all, prefix); len(records) > 0 {
+		if _, err := c.p.DeleteRecords(ctx, c.zone, records); err != nil {
+			t.Errorf("cleaning up: %v", err)
+		}
+	}
+}
+
+// withPrefix returns the records whose name, or the name under the SRV
+// service and transport labels, starts with prefix.
+func withPrefix(records []libdns.Record, prefix string) []libdns.Record {
+	var result []libdns.Record
+	for _, r := range records {
+		labels := strings.Split(r.RR().Name, ".")
+		for len(labels) > 1 && strings.HasPrefix(labels[0], "_") {
+			labels = labels[1:]
+		}
+		if strings.HasPrefix(labels[0], prefix) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func formatRecords(records []libdns.Record) []string {
+	result := []string{}
+	for _, r := range records {
+		rr := r.RR()
+		result = append(result, fmt.Sprintf("%s %d %s %s", rr.Name, int(rr.TTL.Seconds()), rr.Type, rr.Data))
+	}
+	sort.Strings(result)
+	return result
+}
+
+func recordTypes(records []libdns.Record) []string {
+	result := []string{}
+	for _, r := range records {
+		result = append(result, reflect.TypeOf(r).String())
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,1060 @@
+// Package huaweicloudtest provides an in-memory Huawei Cloud DNS server for
+// testing code that uses the huaweicloud package without a cloud account.
+//
+// The server implements the v2 and v2.1 zone and recordset endpoints used by
+// the huaweicloud package, verifies AK/SK signatures, and can inject faults
+// such as throttling, server errors, latency and pending statuses.
+//
+//	server := huaweicloudtest.NewServer(map[string]string{"ak": "sk"})
+//	defer server.Close()
+//	server.AddZone(huaweicloud.Zone{Name: "example.com."})
+//
+//	provider := &huaweicloud.Provider{
+//		AccessKeyId:     "ak",
+//		SecretAccessKey: "sk",
+//		Endpoint:        server.URL,
+//	}
+package huaweicloudtest
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libdns/huaweicloud"
+	"github.com/libdns/huaweicloud/internal/dnsutil"
+)
+
+const (
+	// maxLimit is the largest page size the list endpoints accept.
+	maxLimit = 500
+	// defaultTTL is the TTL of recordsets created without one.
+	defaultTTL = 300
+	// poolId is the pool hosting every zone of the server.
+	poolId = "00000000570e54ee01570e9939b20019"
+	// maxClockSkew is how far the signing time may be from the server time.
+	maxClockSkew = 15 * time.Minute
+)
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Query  neturl.Values
+}
+
+// Fault is an error or a delay injected into matching requests.
+type Fault struct {
+	// Method and Path select the requests the fault applies to. An empty
+	// Method matches any method, and Path matches every path it prefixes.
+	Method string
+	Path   string
+	// Latency delays the response.
+	Latency time.Duration
+	// StatusCode, unless zero, is returned instead of handling the request,
+	// along with Code and Message. RetryAfter sets the Retry-After header.
+	StatusCode int
+	Code       string
+	Message    string
+	RetryAfter string
+	// Count is the number of requests the fault applies to, or 0 for every
+	// matching request.
+	Count int
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	return (f.Method == "" || f.Method == r.Method) && strings.HasPrefix(r.URL.Path, f.Path)
+}
+
+// Server is an in-memory Huawei Cloud DNS API server.
+type Server struct {
+	// URL is the endpoint of the server, to be used as the DNS endpoint.
+	URL string
+
+	server      *httptest.Server
+	credentials map[string]string
+
+	mu           sync.Mutex
+	zones        []*zone
+	deleted      map[string]*recordSet
+	faults       []*Fault
+	requests     []Request
+	pendingPolls int
+	finalStatus  string
+	pageSize     int
+	nextId       int
+	now          func() time.Time
+}
+
+type zone struct {
+	zone       huaweicloud.Zone
+	recordSets []*recordSet
+}
+
+type recordSet struct {
+	rs     huaweicloud.RecordSet
+	zoneId string
+	// pending is the number of polls left in the pending status.
+	pending       int
+	pendingStatus string
+}
+
+// status returns the status reported for the recordset.
+func (rs *recordSet) status() string {
+	if rs.pending > 0 {
+		return rs.pendingStatus
+	}
+	return rs.rs.Status
+}
+
+// NewServer starts a server accepting requests signed with one of the given
+// AK/SK pairs, which map access key IDs to secret access keys. It must be
+// closed with Close.
+func NewServer(credentials map[string]string) *Server {
+	s := &Server{
+		credentials: credentials,
+		deleted:     make(map[string]*recordSet),
+		now:         time.Now,
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Client returns an HTTP client configured for the server.
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// AddZone adds a zone and returns it. The zone defaults to a public zone
+// with a generated ID.
+func (s *Server) AddZone(z huaweicloud.Zone) huaweicloud.Zone {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addZone(z)
+}
+
+// addZone fills in the defaults of the zone and stores it. It is called
+// with s.mu held.
+func (s *Server) addZone(z huaweicloud.Zone) huaweicloud.Zone {
+	if z.Id == "" {
+		z.Id = s.newId()
+	}
+	if z.ZoneType == "" {
+		z.ZoneType = huaweicloud.ZoneTypePublic
+	}
+	if z.Status == "" {
+		z.Status = huaweicloud.StatusActive
+	}
+	z.Name = dnsutil.Fqdn(z.Name)
+	if z.Email == "" {
+		z.Email = "hostmaster@" + strings.TrimSuffix(z.Name, ".")
+	}
+	if z.Ttl == 0 {
+		z.Ttl = defaultTTL
+	}
+	if z.Serial == 0 {
+		z.Serial = 1
+	}
+	if z.PoolId == "" {
+		z.PoolId = poolId
+	}
+	s.zones = append(s.zones, &zone{zone: z})
+	return z
+}
+
+// AddRecordSet adds a recordset to the zone with the given name or ID,
+// bypassing validation, and returns it.
+func (s *Server) AddRecordSet(zoneName string, rs huaweicloud.RecordSet) huaweicloud.RecordSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	z := s.findZone(zoneName)
+	if z == nil {
+		panic(fmt.Sprintf("huaweicloudtest: zone %q not found", zoneName))
+	}
+	if rs.Id == "" {
+		rs.Id = s.newId()
+	}
+	if rs.Status == "" {
+		rs.Status = huaweicloud.StatusActive
+	}
+	if rs.Line == "" {
+		rs.Line = huaweicloud.LineDefault
+	}
+	rs.Name = dnsutil.Fqdn(rs.Name)
+	z.recordSets = append(z.recordSets, &recordSet{rs: rs, zoneId: z.zone.Id})
+	return rs
+}
+
+// RecordSets returns the recordsets of the zone with the given name or ID,
+// in order of creation.
+func (s *Server) RecordSets(zoneName string) []huaweicloud.RecordSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	z := s.findZone(zoneName)
+	if z == nil {
+		return nil
+	}
+	result := make([]huaweicloud.RecordSet, 0, len(z.recordSets))
+	for _, rs := range z.recordSets {
+		result = append(result, rs.rs)
+	}
+	return result
+}
+
+// Inject adds a fault. Faults are applied in the order they were added,
+// after the signature has been verified.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetPending makes created, updated and deleted recordsets report a
+// PENDING_* status for the given number of reads by ID before they settle.
+// Changed recordsets settle in finalStatus, which defaults to ACTIVE and
+// may be ERROR to simulate failed changes.
+func (s *Server) SetPending(polls int, finalStatus string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pendingPolls = polls
+	s.finalStatus = finalStatus
+}
+
+// SetPageSize makes list responses hold at most n items per page, whatever
+// the requested limit, so that clients have to follow pagination. A size of
+// 0 restores the default.
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageSize = n
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// ResetRequests forgets the requests received so far.
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+func (s *Server) newId() string {
+	s.nextId++
+	return fmt.Sprintf("ff8080825b8fc86c%016x", s.nextId)
+}
+
+// findZone returns the zone with the given name or ID.
+func (s *Server) findZone(nameOrId string) *zone {
+	for _, z := range s.zones {
+		if z.zone.Id == nameOrId || strings.EqualFold(z.zone.Name, dnsutil.Fqdn(nameOrId)) {
+			return z
+		}
+	}
+	return nil
+}
+
+// apiError is an error response.
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.code + ": " + e.message
+}
+
+func errorf(status int, code, format string, args ...any) *apiError {
+	return &apiError{status: status, code: code, message: fmt.Sprintf(format, args...)}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(huaweicloud.HeaderXRequestId, strconv.FormatInt(time.Now().UnixNano(), 16))
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, errorf(http.StatusBadRequest, "DNS.0001", "reading body: %v", err))
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query()})
+	s.mu.Unlock()
+
+	if err := s.authenticate(r, body); err != nil {
+		writeError(w, err)
+		return
+	}
+	if fault := s.fault(r); fault != nil {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.StatusCode != 0 {
+			if fault.RetryAfter != "" {
+				w.Header().Set("Retry-After", fault.RetryAfter)
+			}
+			writeError(w, &apiError{status: fault.StatusCode, code: fault.Code, message: fault.Message})
+			return
+		}
+	}
+
+	s.mu.Lock()
+	resp, status, apiErr := s.handle(r, body)
+	s.mu.Unlock()
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// fault returns the first fault matching the request, if any, and counts
+// it against the fault's budget.
+func (s *Server) fault(r *http.Request) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, f := range s.faults {
+		if !f.matches(r) {
+			continue
+		}
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// authenticate verifies the AK/SK signature of the request.
+func (s *Server) authenticate(r *http.Request, body []byte) *apiError {
+	fail := func(format string, args ...any) *apiError {
+		return errorf(http.StatusUnauthorized, "APIGW.0301", "Incorrect IAM authentication information: "+format, args...)
+	}
+
+	auth := r.Header.Get(huaweicloud.HeaderXAuthorization)
+	if !strings.HasPrefix(auth, huaweicloud.SignAlgorithm+" ") {
+		return fail("unsupported authorization %q", auth)
+	}
+	params := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(auth, huaweicloud.SignAlgorithm+" "), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		params[key] = value
+	}
+
+	secret, ok := s.credentials[params["Access"]]
+	if !ok {
+		return fail("unknown access key %q", params["Access"])
+	}
+	date, err := time.Parse(huaweicloud.DateFormat, r.Header.Get(huaweicloud.HeaderXDateTime))
+	if err != nil {
+		return fail("invalid %s header", huaweicloud.HeaderXDateTime)
+	}
+	if skew := s.now().Sub(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return fail("request time %s is too far from the server time", date)
+	}
+
+	signedHeaders := strings.Split(params["SignedHeaders"], ";")
+	expected := signature(r, body, signedHeaders, date.Format(huaweicloud.DateFormat), secret)
+	if !hmac.Equal([]byte(expected), []byte(params["Signature"])) {
+		return fail("verify aksk signature fail")
+	}
+	return nil
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"code": err.code, "message": err.message})
+}
+
+// handle routes the request. It is called with s.mu held.
+func (s *Server) handle(r *http.Request, body []byte) (any, int, *apiError) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	version := segments[0]
+	if version != "v2" && version != "v2.1" {
+		return nil, 0, errorf(http.StatusNotFound, "APIGW.0101", "the API does not exist")
+	}
+	v21 := version == "v2.1"
+	query := r.URL.Query()
+
+	switch route := strings.Join(append([]string{""}, segments[1:]...), "/"); {
+	case route == "/zones" && r.Method == http.MethodGet && !v21:
+		return s.listZones(r.URL, query)
+
+	case route == "/zones" && r.Method == http.MethodPost && !v21:
+		return s.createZone(body)
+
+	case route == "/recordsets/statuses/set" && r.Method == http.MethodPut && v21:
+		return s.batchSetStatus(body)
+
+	case len(segments) == 5 && segments[1] == "recordsets" && segments[3] == "statuses" && segments[4] == "set" && r.Method == http.MethodPut && v21:
+		return s.setStatus(segments[2], body)
+
+	case len(segments) >= 3 && segments[1] == "zones":
+		z := s.findZone(segments[2])
+		if z == nil || z.zone.Id != segments[2] {
+			return nil, 0, errorf(http.StatusNotFound, "DNS.0101", "The zone does not exist.")
+		}
+		if v21 && z.zone.ZoneType != huaweicloud.ZoneTypePublic {
+			return nil, 0, errorf(http.StatusBadRequest, "DNS.0002", "The v2.1 API only supports public zones.")
+		}
+
+		switch {
+		case len(segments) == 3 && r.Method == http.MethodGet && !v21:
+			return z.view(), http.StatusOK, nil
+		case len(segments) == 3 && r.Method == http.MethodPatch && !v21:
+			return s.updateZone(z, body)
+		case len(segments) == 3 && r.Method == http.MethodDelete && !v21:
+			return s.deleteZone(z)
+		case len(segments) == 4 && segments[3] == "nameservers" && r.Method == http.MethodGet && !v21:
+			return s.nameservers(z), http.StatusOK, nil
+		case len(segments) == 4 && segments[3] == "recordsets":
+			switch r.Method {
+			case http.MethodGet:
+				return s.listRecordSets(z, r.URL, query, v21)
+			case http.MethodPost:
+				return s.createRecordSet(z, body, v21)
+			case http.MethodPut:
+				if v21 {
+					return s.batchUpdateRecordSets(z, body)
+				}
+			case http.MethodDelete:
+				if v21 {
+					return s.batchDeleteRecordSets(z, body)
+				}
+			}
+		case len(segments) == 5 && segments[3] == "recordsets":
+			switch r.Method {
+			case http.MethodGet:
+				return s.getRecordSet(z, segments[4], v21)
+			case http.MethodPut:
+				return s.updateRecordSet(z, segments[4], body, v21)
+			case http.MethodDelete:
+				return s.deleteRecordSet(z, segments[4], v21)
+			}
+		}
+	}
+
+	return nil, 0, errorf(http.StatusNotFound, "APIGW.0101", "the API does not exist")
+}
+
+func (s *Server) listZones(u *neturl.URL, query neturl.Values) (any, int, *apiError) {
+	zoneType := query.Get("type")
+	if zoneType == "" {
+		zoneType = huaweicloud.ZoneTypePublic
+	}
+
+	var zones []huaweicloud.Zone
+	for _, z := range s.zones {
+		if z.zone.ZoneType != zoneType || !matchName(z.zone.Name, query) {
+			continue
+		}
+		zones = append(zones, z.view())
+	}
+
+	zones, links, metadata, err := paginate(u, query, s.pageSize, zones, func(z huaweicloud.Zone) string { return z.Id })
+	if err != nil {
+		return nil, 0, err
+	}
+	return huaweicloud.ListZonesResponse{Links: links, Metadata: metadata, Zones: zones}, http.StatusOK, nil
+}
+
+func (s *Server) createZone(body []byte) (any, int, *apiError) {
+	var req huaweicloud.CreateZoneRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0001", "Invalid request body: %v", err)
+	}
+
+	z := huaweicloud.Zone{
+		Name:        dnsutil.Fqdn(req.Name),
+		ZoneType:    req.ZoneType,
+		Email:       req.Email,
+		Ttl:         req.Ttl,
+		Description: req.Description,
+	}
+	if z.ZoneType == "" {
+		z.ZoneType = huaweicloud.ZoneTypePublic
+	}
+	switch {
+	case req.Name == "" || strings.Count(z.Name, ".") < 2:
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0202", "Invalid zone name %q.", req.Name)
+	case z.ZoneType != huaweicloud.ZoneTypePublic && z.ZoneType != huaweicloud.ZoneTypePrivate:
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0204", "Invalid zone type %q.", req.ZoneType)
+	case z.Ttl < 0:
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0307", "Invalid TTL %d.", req.Ttl)
+	case z.ZoneType == huaweicloud.ZoneTypePrivate && (req.Router == nil || req.Router.RouterId == ""):
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0205", "A router is required to create a private zone.")
+	case z.ZoneType == huaweicloud.ZoneTypePublic && req.Router != nil:
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0205", "Public zones cannot be associated with a router.")
+	}
+	for _, other := range s.zones {
+		if other.zone.ZoneType == z.ZoneType && strings.EqualFold(other.zone.Name, z.Name) {
+			return nil, 0, errorf(http.StatusConflict, "DNS.0201", "The zone %s already exists.", z.Name)
+		}
+	}
+	if req.Router != nil {
+		router := *req.Router
+		router.Status = huaweicloud.StatusActive
+		z.Routers = []huaweicloud.Router{router}
+	}
+
+	z = s.addZone(z)
+	// The zone is reported as being created, and is active afterwards.
+	z.Status = huaweicloud.StatusPendingCreate
+	return z, http.StatusAccepted, nil
+}
+
+func (s *Server) updateZone(z *zone, body []byte) (any, int, *apiError) {
+	var req huaweicloud.UpdateZoneRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0001", "Invalid request body: %v", err)
+	}
+	if req.Ttl < 0 {
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0307", "Invalid TTL %d.", req.Ttl)
+	}
+
+	if req.Email != "" {
+		z.zone.Email = req.Email
+	}
+	if req.Ttl != 0 {
+		z.zone.Ttl = req.Ttl
+	}
+	if req.Description != "" {
+		z.zone.Description = req.Description
+	}
+	z.zone.Serial++
+	return z.view(), http.StatusAccepted, nil
+}
+
+func (s *Server) deleteZone(z *zone) (any, int, *apiError) {
+	for i, other := range s.zones {
+		if other == z {
+			s.zones = append(s.zones[:i:i], s.zones[i+1:]...)
+			break
+		}
+	}
+
+	view := z.view()
+	view.Status = huaweicloud.StatusPendingDelete
+	return view, http.StatusAccepted, nil
+}
+
+func (s *Server) nameservers(z *zone) huaweicloud.ListNameserversResponse {
+	if z.zone.ZoneType == huaweicloud.ZoneTypePrivate {
+		return huaweicloud.ListNameserversResponse{Nameservers: []huaweicloud.Nameserver{
+			{Address: "100.125.1.250", Priority: 1},
+			{Address: "100.125.64.250", Priority: 2},
+		}}
+	}
+	var nameservers []huaweicloud.Nameserver
+	for i, tld := range []string{"com", "cn", "net", "org"} {
+		nameservers = append(nameservers, huaweicloud.Nameserver{
+			Hostname: "ns1.huaweicloud-dns." + tld + ".",
+			Priority: int32(i + 1),
+		})
+	}
+	return huaweicloud.ListNameserversResponse{Nameservers: nameservers}
+}
+
+func (s *Server) listRecordSets(z *zone, u *neturl.URL, query neturl.Values, v21 bool) (any, int, *apiError) {
+	var recordSets []huaweicloud.RecordSet
+	for _, rs := range z.recordSets {
+		if !matchName(rs.rs.Name, query) {
+			continue
+		}
+		if t := query.Get("type"); t != "" && !strings.EqualFold(t, rs.rs.Type) {
+			continue
+		}
+		if status := query.Get("status"); status != "" && status != rs.status() {
+			continue
+		}
+		if line := query.Get("line_id"); v21 && line != "" && line != rs.rs.Line {
+			continue
+		}
+		recordSets = append(recordSets, s.view(rs, v21))
+	}
+
+	recordSets, links, metadata, err := paginate(u, query, s.pageSize, recordSets, func(rs huaweicloud.RecordSet) string { return rs.Id })
+	if err != nil {
+		return nil, 0, err
+	}
+	return huaweicloud.ListRecordsResponse{Links: links, Metadata: metadata, RecordSets: recordSets}, http.StatusOK, nil
+}
+
+func (s *Server) getRecordSet(z *zone, id string, v21 bool) (any, int, *apiError) {
+	rs := z.recordSet(id)
+	if rs == nil {
+		rs = s.deleted[id]
+		if rs == nil || rs.zoneId != z.zone.Id {
+			return nil, 0, errorf(http.StatusNotFound, "DNS.0305", "The record set does not exist.")
+		}
+	}
+
+	view := s.view(rs, v21)
+	if rs.pending > 0 {
+		rs.pending--
+		if rs.pending == 0 && rs.pendingStatus == huaweicloud.StatusPendingDelete {
+			delete(s.deleted, id)
+		}
+	}
+	return view, http.StatusOK, nil
+}
+
+func (s *Server) createRecordSet(z *zone, body []byte, v21 bool) (any, int, *apiError) {
+	var req huaweicloud.RecordSet
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0001", "Invalid request body: %v", err)
+	}
+	if !v21 && (req.Line != "" || req.Weight != nil) {
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0001", "Line and weight are only supported by the v2.1 API.")
+	}
+
+	rs := &recordSet{zoneId: z.zone.Id, rs: huaweicloud.RecordSet{
+		Id:          s.newId(),
+		Name:        dnsutil.Fqdn(req.Name),
+		Type:        strings.ToUpper(req.Type),
+		Ttl:         req.Ttl,
+		Records:     req.Records,
+		Description: req.Description,
+		Status:      huaweicloud.StatusActive,
+		Line:        req.Line,
+		Weight:      req.Weight,
+	}}
+	if rs.rs.Ttl == 0 {
+		rs.rs.Ttl = defaultTTL
+	}
+	if rs.rs.Line == "" {
+		rs.rs.Line = huaweicloud.LineDefault
+	}
+	if err := z.validate(rs.rs); err != nil {
+		return nil, 0, err
+	}
+
+	z.recordSets = append(z.recordSets, rs)
+	s.markPending(rs, huaweicloud.StatusPendingCreate)
+	return s.view(rs, v21), http.StatusAccepted, nil
+}
+
+func (s *Server) updateRecordSet(z *zone, id string, body []byte, v21 bool) (any, int, *apiError) {
+	var req huaweicloud.RecordSet
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0001", "Invalid request body: %v", err)
+	}
+	rs := z.recordSet(id)
+	if rs == nil {
+		return nil, 0, errorf(http.StatusNotFound, "DNS.0305", "The record set does not exist.")
+	}
+
+	updated, err := z.update(rs, req, v21)
+	if err != nil {
+		return nil, 0, err
+	}
+	rs.rs = updated
+	s.markPending(rs, huaweicloud.StatusPendingUpdate)
+	return s.view(rs, v21), http.StatusAccepted, nil
+}
+
+func (s *Server) deleteRecordSet(z *zone, id string, v21 bool) (any, int, *apiError) {
+	rs := z.recordSet(id)
+	if rs == nil {
+		return nil, 0, errorf(http.StatusNotFound, "DNS.0305", "The record set does not exist.")
+	}
+
+	z.remove(id)
+	s.markDeleted(rs)
+	return s.view(rs, v21), http.StatusAccepted, nil
+}
+
+func (s *Server) batchUpdateRecordSets(z *zone, body []byte) (any, int, *apiError) {
+	var req struct {
+		RecordSets []huaweicloud.RecordSet `json:"recordsets"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0001", "Invalid request body: %v", err)
+	}
+
+	// Validate every update before applying any, as the request fails as a
+	// whole.
+	updates := make([]huaweicloud.RecordSet, len(req.RecordSets))
+	for i, update := range req.RecordSets {
+		rs := z.recordSet(update.Id)
+		if rs == nil {
+			return nil, 0, errorf(http.StatusNotFound, "DNS.0305", "The record set %s does not exist.", update.Id)
+		}
+		updated, err := z.update(rs, update, true)
+		if err != nil {
+			return nil, 0, err
+		}
+		updates[i] = updated
+	}
+
+	var resp huaweicloud.ListRecordsResponse
+	for i, updated := range updates {
+		rs := z.recordSet(req.RecordSets[i].Id)
+		rs.rs = updated
+		s.markPending(rs, huaweicloud.StatusPendingUpdate)
+		resp.RecordSets = append(resp.RecordSets, s.view(rs, true))
+	}
+	return resp, http.StatusOK, nil
+}
+
+func (s *Server) batchDeleteRecordSets(z *zone, body []byte) (any, int, *apiError) {
+	var req struct {
+		RecordsetIds []string `json:"recordset_ids"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0001", "Invalid request body: %v", err)
+	}
+	for _, id := range req.RecordsetIds {
+		if z.recordSet(id) == nil {
+			return nil, 0, errorf(http.StatusNotFound, "DNS.0305", "The record set %s does not exist.", id)
+		}
+	}
+
+	var resp huaweicloud.ListRecordsResponse
+	for _, id := range req.RecordsetIds {
+		rs := z.recordSet(id)
+		if rs == nil {
+			// Listed twice.
+			continue
+		}
+		z.remove(id)
+		s.markDeleted(rs)
+		resp.RecordSets = append(resp.RecordSets, s.view(rs, true))
+	}
+	return resp, http.StatusOK, nil
+}
+
+func (s *Server) setStatus(id string, body []byte) (any, int, *apiError) {
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0001", "Invalid request body: %v", err)
+	}
+	status, err := recordSetStatus(req.Status)
+	if err != nil {
+		return nil, 0, err
+	}
+	rs := s.recordSet(id)
+	if rs == nil {
+		return nil, 0, errorf(http.StatusNotFound, "DNS.0305", "The record set does not exist.")
+	}
+
+	rs.rs.Status = status
+	return s.view(rs, true), http.StatusOK, nil
+}
+
+func (s *Server) batchSetStatus(body []byte) (any, int, *apiError) {
+	var req struct {
+		Status       string   `json:"status"`
+		RecordsetIds []string `json:"recordset_ids"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, 0, errorf(http.StatusBadRequest, "DNS.0001", "Invalid request body: %v", err)
+	}
+	status, err := recordSetStatus(req.Status)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, id := range req.RecordsetIds {
+		if s.recordSet(id) == nil {
+			return nil, 0, errorf(http.StatusNotFound, "DNS.0305", "The record set %s does not exist.", id)
+		}
+	}
+
+	var resp huaweicloud.ListRecordsResponse
+	for _, id := range req.RecordsetIds {
+		rs := s.recordSet(id)
+		rs.rs.Status = status
+		resp.RecordSets = append(resp.RecordSets, huaweicloud.RecordSet{Id: id, Status: status})
+	}
+	return resp, http.StatusOK, nil
+}
+
+func recordSetStatus(status string) (string, *apiError) {
+	switch status {
+	case huaweicloud.StatusEnable:
+		return huaweicloud.StatusActive, nil
+	case huaweicloud.StatusDisable:
+		return huaweicloud.StatusDisable, nil
+	default:
+		return "", errorf(http.StatusBadRequest, "DNS.0001", "Invalid status %q, expected ENABLE or DISABLE.", status)
+	}
+}
+
+// recordSet returns the recordset with the given ID in any zone.
+func (s *Server) recordSet(id string) *recordSet {
+	for _, z := range s.zones {
+		if rs := z.recordSet(id); rs != nil {
+			return rs
+		}
+	}
+	return nil
+}
+
+// markPending puts a changed recordset in the pending status, if enabled.
+func (s *Server) markPending(rs *recordSet, status string) {
+	if s.finalStatus != "" {
+		rs.rs.Status = s.finalStatus
+	}
+	rs.pending, rs.pendingStatus = s.pendingPolls, status
+}
+
+// markDeleted keeps a deleted recordset readable by ID while it is pending.
+func (s *Server) markDeleted(rs *recordSet) {
+	s.markPending(rs, huaweicloud.StatusPendingDelete)
+	if rs.pending > 0 {
+		s.deleted[rs.rs.Id] = rs
+	}
+}
+
+// view returns the recordset as returned by the API version.
+func (s *Server) view(rs *recordSet, v21 bool) huaweicloud.RecordSet {
+	view := rs.rs
+	view.Status = rs.status()
+	view.Records = append([]string(nil), rs.rs.Records...)
+	if !v21 {
+		view.Line, view.Weight = "", nil
+	}
+	return view
+}
+
+func (z *zone) recordSet(id string) *recordSet {
+	for _, rs := range z.recordSets {
+		if rs.rs.Id == id {
+			return rs
+		}
+	}
+	return nil
+}
+
+// view returns the zone as returned by the API.
+func (z *zone) view() huaweicloud.Zone {
+	view := z.zone
+	view.RecordNum = int32(len(z.recordSets))
+	return view
+}
+
+func (z *zone) remove(id string) {
+	for i, rs := range z.recordSets {
+		if rs.rs.Id == id {
+			z.recordSets = append(z.recordSets[:i:i], z.recordSets[i+1:]...)
+			return
+		}
+	}
+}
+
+// update returns the recordset with the update applied, after validating
+// it. Empty fields of the update are left unchanged.
+func (z *zone) update(rs *recordSet, update huaweicloud.RecordSet, v21 bool) (huaweicloud.RecordSet, *apiError) {
+	if !v21 && update.Weight != nil {
+		return huaweicloud.RecordSet{}, errorf(http.StatusBadRequest, "DNS.0001", "Weight is only supported by the v2.1 API.")
+	}
+
+	updated := rs.rs
+	if update.Name != "" {
+		updated.Name = dnsutil.Fqdn(update.Name)
+	}
+	if update.Type != "" {
+		updated.Type = strings.ToUpper(update.Type)
+	}
+	if update.Ttl != 0 {
+		updated.Ttl = update.Ttl
+	}
+	if update.Records != nil {
+		updated.Records = update.Records
+	}
+	if update.Description != "" {
+		updated.Description = update.Description
+	}
+	if update.Weight != nil {
+		updated.Weight = update.Weight
+	}
+	if err := z.validate(updated); err != nil {
+		return huaweicloud.RecordSet{}, err
+	}
+	return updated, nil
+}
+
+// validate checks a recordset to be stored in the zone, which must not
+// hold another recordset with the same name, type and line.
+func (z *zone) validate(rs huaweicloud.RecordSet) *apiError {
+	name := strings.ToLower(rs.Name)
+	zoneName := strings.ToLower(z.zone.Name)
+	if name != zoneName && !strings.HasSuffix(name, "."+zoneName) {
+		return errorf(http.StatusBadRequest, "DNS.0303", "The record set name %s does not belong to zone %s.", rs.Name, z.zone.Name)
+	}
+	if !dnsutil.SupportedTypes[rs.Type] {
+		return errorf(http.StatusBadRequest, "DNS.0304", "Unsupported record set type %q.", rs.Type)
+	}
+	if rs.Ttl < 1 {
+		return errorf(http.StatusBadRequest, "DNS.0307", "Invalid TTL %d.", rs.Ttl)
+	}
+	if len(rs.Records) == 0 {
+		return errorf(http.StatusBadRequest, "DNS.0308", "The record set has no records.")
+	}
+	if rs.Weight != nil && (*rs.Weight < 0 || *rs.Weight > 1000) {
+		return errorf(http.StatusBadRequest, "DNS.0309", "Invalid weight %d.", *rs.Weight)
+	}
+	seen := make(map[string]bool)
+	for _, value := range rs.Records {
+		if seen[value] {
+			return errorf(http.StatusBadRequest, "DNS.0308", "Duplicate record %q.", value)
+		}
+		seen[value] = true
+		if rs.Type == "TXT" {
+			if err := validateTXT(value); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, other := range z.recordSets {
+		if other.rs.Id != rs.Id && strings.EqualFold(other.rs.Name, rs.Name) && other.rs.Type == rs.Type && other.rs.Line == rs.Line {
+			return errorf(http.StatusBadRequest, "DNS.0312", "Attribute 'name' conflicts: a %s record set named %s already exists.", rs.Type, rs.Name)
+		}
+	}
+	return nil
+}
+
+// validateTXT checks that a TXT value is made of quoted character-strings
+// of at most 255 bytes each, separated by spaces.
+func validateTXT(value string) *apiError {
+	for i := 0; i < len(value); i++ {
+		if value[i] == ' ' {
+			continue
+		}
+		if value[i] != '"' {
+			return errorf(http.StatusBadRequest, "DNS.0308", "TXT record %s must be enclosed in quotation marks.", value)
+		}
+		n := 0
+		for i++; i < len(value) && value[i] != '"'; i++ {
+			if value[i] == '\\' && i+3 < len(value) && dnsutil.IsDigit(value[i+1]) {
+				i += 3
+			} else if value[i] == '\\' {
+				i++
+			}
+			n++
+		}
+		if i >= len(value) {
+			return errorf(http.StatusBadRequest, "DNS.0308", "TXT record %s must be enclosed in quotation marks.", value)
+		}
+		if n > 255 {
+			return errorf(http.StatusBadRequest, "DNS.0308", "TXT record string exceeds 255 characters.")
+		}
+	}
+	if value == "" {
+		return errorf(http.StatusBadRequest, "DNS.0308", "TXT record must not be empty.")
+	}
+	return nil
+}
+
+// matchName reports whether name matches the name and search_mode query
+// parameters. Names are matched exactly with search_mode "equal", and as
+// substrings otherwise.
+func matchName(name string, query neturl.Values) bool {
+	want := query.Get("name")
+	if want == "" {
+		return true
+	}
+	if query.Get("search_mode") == "equal" {
+		return strings.EqualFold(dnsutil.Fqdn(name), dnsutil.Fqdn(want))
+	}
+	return strings.Contains(strings.ToLower(name), strings.ToLower(want))
+}
+
+// paginate returns the page selected by the limit, offset and marker query
+// parameters, with the links and metadata of a paginated response. Pages
+// hold at most pageSize items, unless it is 0.
+func paginate[T any](u *neturl.URL, query neturl.Values, pageSize int, items []T, id func(T) string) ([]T, *huaweicloud.Links, *huaweicloud.Metadata, *apiError) {
+	limit := maxLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxLimit {
+			return nil, nil, nil, errorf(http.StatusBadRequest, "DNS.0001", "Invalid limit %q.", v)
+		}
+		if n > 0 {
+			limit = n
+		}
+	}
+	if pageSize > 0 && limit > pageSize {
+		limit = pageSize
+	}
+	start := 0
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, nil, nil, errorf(http.StatusBadRequest, "DNS.0001", "Invalid offset %q.", v)
+		}
+		start = n
+	}
+	if marker := query.Get("marker"); marker != "" {
+		index := len(items)
+		for i, item := range items {
+			if id(item) == marker {
+				index = i + 1
+			}
+		}
+		start = index
+	}
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+
+	self := *u
+	links := &huaweicloud.Links{Self: self.String()}
+	if end < len(items) {
+		next := *u
+		q := next.Query()
+		q.Del("marker")
+		q.Set("offset", strconv.Itoa(end))
+		q.Set("limit", strconv.Itoa(limit))
+		next.RawQuery = q.Encode()
+		links.Next = next.String()
+	}
+	return items[start:end], links, &huaweicloud.Metadata{TotalCount: int32(len(items))}, nil
+}
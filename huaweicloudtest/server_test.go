@@ -0,0 +1,180 @@
+package huaweicloudtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/huaweicloud"
+)
+
+func newClient(server *Server, secret string) *huaweicloud.Client {
+	policy := huaweicloud.DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	return huaweicloud.NewClient("ak", secret, "", huaweicloud.WithEndpoint(server.URL), huaweicloud.WithRetryPolicy(policy))
+}
+
+func TestServerSignature(t *testing.T) {
+	server := NewServer(map[string]string{"ak": "sk"})
+	defer server.Close()
+	server.AddZone(huaweicloud.Zone{Name: "example.com"})
+
+	if _, err := newClient(server, "sk").ListZones(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err := newClient(server, "wrong").ListZones(context.Background())
+	if !huaweicloud.IsAuth(err) {
+		t.Fatalf("expected an authentication error, got %v", err)
+	}
+}
+
+// TestSignature checks the server's signature against vectors computed
+// independently of both the client and the server.
+func TestSignature(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		body          string
+		header        map[string]string
+		signedHeaders string
+		signature     string
+	}{
+		{
+			name:          "query",
+			method:        http.MethodGet,
+			url:           "https://dns.cn-south-1.myhuaweicloud.com/v2/zones?search_mode=equal&name=example.com",
+			header:        map[string]string{huaweicloud.HeaderXSecurityToken: "token-value"},
+			signedHeaders: "x-sdk-date;x-security-token",
+			signature:     "78b0b604f2134a27ee8ae5310d77e574dea8497ee83b86d8024d4c37fca58314",
+		},
+		{
+			name:          "body",
+			method:        http.MethodPost,
+			url:           "https://dns.cn-south-1.myhuaweicloud.com/v2/zones/zone-id/recordsets",
+			body:          `{"name":"www.example.com.","type":"A","ttl":300,"records":["192.0.2.1"]}`,
+			header:        map[string]string{"Content-Type": "application/json"},
+			signedHeaders: "content-type;x-sdk-date",
+			signature:     "233a8279bdbc4a91a7eaa1429635de7a4d5bacb47a29392e7f280deaa5e1922d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set(huaweicloud.HeaderXDateTime, "20240101T000000Z")
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+
+			got := signature(r, []byte(tt.body), strings.Split(tt.signedHeaders, ";"), "20240101T000000Z", "secret-key")
+			if got != tt.signature {
+				t.Errorf("expected signature %s, got %s", tt.signature, got)
+			}
+		})
+	}
+}
+
+func TestServerRecordSets(t *testing.T) {
+	server := NewServer(map[string]string{"ak": "sk"})
+	defer server.Close()
+	server.AddZone(huaweicloud.Zone{Name: "example.com."})
+	client := newClient(server, "sk")
+	ctx := context.Background()
+
+	rs, err := client.AppendRecord(ctx, "example.com.", huaweicloud.RecordSet{Name: "www.example.com.", Type: "A", Records: []string{"192.0.2.1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rs.Id == "" || rs.Ttl != defaultTTL || rs.Status != huaweicloud.StatusActive {
+		t.Errorf("unexpected recordset %+v", rs)
+	}
+
+	_, err = client.AppendRecord(ctx, "example.com.", huaweicloud.RecordSet{Name: "WWW.example.com", Type: "A", Records: []string{"192.0.2.2"}})
+	if !huaweicloud.IsConflict(err) {
+		t.Errorf("expected a conflict for a second A recordset, got %v", err)
+	}
+	_, err = client.AppendRecord(ctx, "example.com.", huaweicloud.RecordSet{Name: "www.example.org.", Type: "A", Records: []string{"192.0.2.2"}})
+	if err == nil {
+		t.Error("expected an error for a name outside the zone")
+	}
+
+	server.Inject(Fault{Method: http.MethodDelete, StatusCode: http.StatusTooManyRequests, Code: "APIGW.0308", Count: 1})
+	server.SetPending(2, "")
+	if _, err := client.DeleteRecord(ctx, "example.com.", rs.Id); err != nil {
+		t.Fatalf("expected the throttled delete to be retried, got %v", err)
+	}
+	for _, status := range []string{huaweicloud.StatusPendingDelete, huaweicloud.StatusPendingDelete} {
+		got, err := client.GetRecordSet(ctx, "example.com.", rs.Id)
+		if err != nil || got.Status != status {
+			t.Fatalf("expected status %s, got %+v, %v", status, got, err)
+		}
+	}
+	if _, err := client.GetRecordSet(ctx, "example.com.", rs.Id); !huaweicloud.IsNotFound(err) {
+		t.Errorf("expected the recordset to be gone once settled, got %v", err)
+	}
+}
+
+func TestServerLatency(t *testing.T) {
+	server := NewServer(map[string]string{"ak": "sk"})
+	defer server.Close()
+	server.Inject(Fault{Latency: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := newClient(server, "sk").ListZones(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded, got %v", err)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	id := func(s string) string { return s }
+	u, _ := neturl.Parse("/v2/zones?limit=2")
+
+	page, links, metadata, err := paginate(u, u.Query(), 0, items, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || metadata.TotalCount != 5 {
+		t.Fatalf("unexpected first page %v, %+v", page, metadata)
+	}
+
+	next, _ := neturl.Parse(links.Next)
+	page, links, _, _ = paginate(next, next.Query(), 0, items, id)
+	if len(page) != 2 || page[0] != "c" || links.Next == "" {
+		t.Errorf("unexpected second page %v, %+v", page, links)
+	}
+
+	marker, _ := neturl.Parse("/v2/zones?limit=2&marker=d")
+	page, links, _, _ = paginate(marker, marker.Query(), 0, items, id)
+	if len(page) != 1 || page[0] != "e" || links.Next != "" {
+		t.Errorf("unexpected page after marker %v, %+v", page, links)
+	}
+}
+
+func TestValidateTXT(t *testing.T) {
+	long := strings.Repeat("a", 255)
+	for value, valid := range map[string]bool{
+		`"hello"`:                       true,
+		`"a" "b"`:                       true,
+		`"say \"hi\" \009"`:             true,
+		`"` + long + `"`:                true,
+		`"` + long + `" "` + long + `"`: true,
+		`"` + long + `a"`:               false,
+		`hello`:                         false,
+		`"hello`:                        false,
+		``:                              false,
+	} {
+		if err := validateTXT(value); (err == nil) != valid {
+			t.Errorf("validating %q: expected valid %v, got %v", value, valid, err)
+		}
+	}
+}
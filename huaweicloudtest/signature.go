@@ -0,0 +1,94 @@
+package huaweicloudtest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/libdns/huaweicloud"
+)
+
+// signature computes the SDK-HMAC-SHA256 signature of a request. It follows
+// the API Gateway documentation rather than the client's signer, so that
+// the server catches signing bugs instead of sharing them.
+func signature(r *http.Request, body []byte, signedHeaders []string, date, secret string) string {
+	segments := strings.Split(r.URL.Path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	uri := strings.Join(segments, "/")
+	if !strings.HasSuffix(uri, "/") {
+		uri += "/"
+	}
+
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		values := r.Header.Values(name)
+		if name == "host" {
+			values = []string{r.Host}
+		}
+		values = append([]string(nil), values...)
+		sort.Strings(values)
+		for _, value := range values {
+			headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+		}
+	}
+
+	payloadHash := r.Header.Get(huaweicloud.HeaderXContentSha256)
+	if payloadHash == "" {
+		payloadHash = sha256Hex(body)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		uri,
+		strings.Join(params, "&"),
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	stringToSign := huaweicloud.SignAlgorithm + "\n" + date + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(stringToSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// uriEncode percent-encodes every byte except the RFC 3986 unreserved
+// characters.
+func uriEncode(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
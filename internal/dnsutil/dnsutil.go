@@ -0,0 +1,22 @@
+// Package dnsutil holds the DNS helpers shared by the huaweicloud package
+// and its test server.
+package dnsutil
+
+import "strings"
+
+// SupportedTypes are the record types Huawei Cloud DNS supports. PTR records
+// are only accepted in private zones.
+var SupportedTypes = map[string]bool{
+	"A": true, "AAAA": true, "CNAME": true, "MX": true, "TXT": true, "NS": true,
+	"SRV": true, "CAA": true, "PTR": true, "SVCB": true, "HTTPS": true,
+}
+
+// Fqdn returns name with a trailing dot, as Huawei Cloud returns names.
+func Fqdn(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
+
+// IsDigit reports whether c is an ASCII digit.
+func IsDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
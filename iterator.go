@@ -0,0 +1,135 @@
+package huaweicloud
+
+import (
+	"context"
+	neturl "net/url"
+	"strconv"
+)
+
+// pageLimit is the page size requested from the list APIs, which is the
+// maximum Huawei Cloud DNS accepts.
+const pageLimit = 500
+
+// pageFetcher fetches a single page of a list API for the given query.
+type pageFetcher[T any] func(ctx context.Context, query neturl.Values) ([]T, *Links, *Metadata, error)
+
+// Iterator walks a paginated Huawei Cloud DNS list API, fetching one page
+// at a time so that large result sets do not have to be held in memory.
+//
+//	it := client.IterRecords(ctx, "example.com.")
+//	for it.Next() {
+//		rs := it.Value()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator[T any] struct {
+	ctx   context.Context
+	fetch pageFetcher[T]
+	query neturl.Values
+
+	page []T
+	cur  T
+	seen int
+	done bool
+	err  error
+
+	// then is continued with once this iterator is exhausted.
+	then *Iterator[T]
+}
+
+func newIterator[T any](ctx context.Context, query neturl.Values, fetch pageFetcher[T]) *Iterator[T] {
+	q := neturl.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("limit", strconv.Itoa(pageLimit))
+	return &Iterator[T]{ctx: ctx, fetch: fetch, query: q}
+}
+
+// Next advances the iterator to the next item, fetching the next page if
+// needed. It returns false when there are no more items or an error occurred.
+func (it *Iterator[T]) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil {
+			return false
+		}
+		if it.done {
+			if it.then == nil {
+				return false
+			}
+			*it = *it.then
+			continue
+		}
+		it.fetchPage()
+	}
+	it.cur, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Value returns the current item.
+func (it *Iterator[T]) Value() T {
+	return it.cur
+}
+
+// Err returns the first error encountered while fetching pages.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// All drains the iterator and returns every remaining item.
+func (it *Iterator[T]) All() ([]T, error) {
+	var items []T
+	for it.Next() {
+		items = append(items, it.Value())
+	}
+	return items, it.Err()
+}
+
+func (it *Iterator[T]) fetchPage() {
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return
+	}
+
+	items, links, meta, err := it.fetch(it.ctx, it.query)
+	if err != nil {
+		it.err = err
+		return
+	}
+	it.page = items
+	it.seen += len(items)
+	it.done = true
+
+	if len(items) == 0 {
+		return
+	}
+
+	// Prefer the marker carried by the next link, and fall back to an
+	// offset when only the total count is known.
+	if links != nil && links.Next != "" {
+		if next, err := neturl.Parse(links.Next); err == nil {
+			nextQuery := next.Query()
+			if nextQuery.Get("marker") != "" || nextQuery.Get("offset") != "" {
+				query := neturl.Values{}
+				for k, v := range it.query {
+					query[k] = v
+				}
+				for k, v := range nextQuery {
+					query[k] = v
+				}
+				if query.Encode() != it.query.Encode() {
+					it.query = query
+					it.done = false
+					return
+				}
+			}
+		}
+	}
+	if meta != nil && it.seen < int(meta.TotalCount) {
+		it.query.Del("marker")
+		it.query.Set("offset", strconv.Itoa(it.seen))
+		it.done = false
+	}
+}
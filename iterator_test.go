@@ -0,0 +1,87 @@
+package huaweicloud
+
+import (
+	"context"
+	neturl "net/url"
+	"strconv"
+	"testing"
+)
+
+func TestIterator(t *testing.T) {
+	const total = 1234
+
+	tests := []struct {
+		name  string
+		links bool
+	}{
+		{name: "marker", links: true},
+		{name: "offset", links: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			fetch := func(ctx context.Context, query neturl.Values) ([]int, *Links, *Metadata, error) {
+				calls++
+				limit, _ := strconv.Atoi(query.Get("limit"))
+				start, _ := strconv.Atoi(query.Get("offset"))
+				if marker := query.Get("marker"); marker != "" {
+					start, _ = strconv.Atoi(marker)
+					start++
+				}
+				var items []int
+				for i := start; i < total && len(items) < limit; i++ {
+					items = append(items, i)
+				}
+				var links *Links
+				if tt.links && start+len(items) < total {
+					links = &Links{Next: "https://dns.example.com/v2/zones?limit=500&marker=" + strconv.Itoa(items[len(items)-1])}
+				}
+				return items, links, &Metadata{TotalCount: total}, nil
+			}
+
+			items, err := newIterator(context.Background(), nil, fetch).All()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(items) != total {
+				t.Fatalf("expected %d items, got %d", total, len(items))
+			}
+			for i, item := range items {
+				if item != i {
+					t.Fatalf("item %d: expected %d, got %d", i, i, item)
+				}
+			}
+			if calls != 3 {
+				t.Errorf("expected 3 page requests, got %d", calls)
+			}
+		})
+	}
+}
+
+func TestIteratorThen(t *testing.T) {
+	page := func(items ...string) pageFetcher[string] {
+		return func(ctx context.Context, query neturl.Values) ([]string, *Links, *Metadata, error) {
+			return items, nil, nil, nil
+		}
+	}
+
+	ctx := context.Background()
+	it := newIterator(ctx, nil, page("public.example.com."))
+	it.then = newIterator(ctx, nil, page())
+	it.then.then = newIterator(ctx, nil, page("private.example.com.", "internal.example.com."))
+
+	items, err := it.All()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"public.example.com.", "private.example.com.", "internal.example.com."}
+	if len(items) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, items)
+	}
+	for i := range expected {
+		if items[i] != expected[i] {
+			t.Errorf("item %d: expected %q, got %q", i, expected[i], items[i])
+		}
+	}
+}
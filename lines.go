@@ -0,0 +1,113 @@
+package huaweicloud
+
+import (
+	"github.com/libdns/libdns"
+)
+
+// LineDefault is the resolution line answering queries that match no other
+// line. Recordsets created without a line belong to it.
+const LineDefault = "default_view"
+
+// WithResolutionLines makes the recordset methods use the v2.1 API, which
+// supports resolution lines (answers depending on the ISP or region of the
+// resolver) and weights. It is only available for public zones.
+func WithResolutionLines() ClientOption {
+	return func(c *Client) {
+		c.lines = true
+	}
+}
+
+// RecordData is the Huawei Cloud specific data of a record. It is returned
+// in the ProviderData field of libdns records when resolution lines are
+// enabled or the record is disabled, and read from it, as a RecordData or
+// *RecordData, when records are written.
+type RecordData struct {
+	// Line is the resolution line of the record, such as "Dianxin",
+	// "Liantong" or "Yidong". It defaults to LineDefault.
+	Line string
+	// Weight is the weight of the recordset among the recordsets of the
+	// same name, type and line, from 0 to 1000.
+	Weight *int32
+	// Disabled is true if the recordset of the record is disabled. It is
+	// ignored when records are written; see Provider.DisableRecords.
+	Disabled bool
+}
+
+// lineOrDefault returns line, or LineDefault if it is empty.
+func lineOrDefault(line string) string {
+	if line == "" {
+		return LineDefault
+	}
+	return line
+}
+
+// sameLine reports whether a and b are the same resolution line.
+func sameLine(a, b string) bool {
+	return lineOrDefault(a) == lineOrDefault(b)
+}
+
+// recordData returns the RecordData carried by a libdns record, if any.
+func recordData(r libdns.Record) (RecordData, bool) {
+	var data any
+	switch rec := r.(type) {
+	case libdns.Address:
+		data = rec.ProviderData
+	case libdns.CAA:
+		data = rec.ProviderData
+	case libdns.CNAME:
+		data = rec.ProviderData
+	case libdns.MX:
+		data = rec.ProviderData
+	case libdns.NS:
+		data = rec.ProviderData
+	case libdns.SRV:
+		data = rec.ProviderData
+	case libdns.ServiceBinding:
+		data = rec.ProviderData
+	case libdns.TXT:
+		data = rec.ProviderData
+	}
+
+	switch d := data.(type) {
+	case RecordData:
+		return d, true
+	case *RecordData:
+		if d != nil {
+			return *d, true
+		}
+	}
+	return RecordData{}, false
+}
+
+// withRecordData returns the record with its ProviderData set to data.
+// Records of types without a ProviderData field are returned unchanged.
+func withRecordData(r libdns.Record, data RecordData) libdns.Record {
+	switch rec := r.(type) {
+	case libdns.Address:
+		rec.ProviderData = data
+		return rec
+	case libdns.CAA:
+		rec.ProviderData = data
+		return rec
+	case libdns.CNAME:
+		rec.ProviderData = data
+		return rec
+	case libdns.MX:
+		rec.ProviderData = data
+		return rec
+	case libdns.NS:
+		rec.ProviderData = data
+		return rec
+	case libdns.SRV:
+		rec.ProviderData = data
+		return rec
+	case libdns.ServiceBinding:
+		rec.ProviderData = data
+		return rec
+	case libdns.TXT:
+		rec.ProviderData = data
+		return rec
+	default:
+		return r
+	}
+}
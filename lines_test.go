@@ -0,0 +1,92 @@
+package huaweicloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestResolutionLines(t *testing.T) {
+	weight := int32(10)
+	existing := []RecordSet{
+		{Id: "default-id", Name: "www.example.com.", Type: "A", Ttl: 300, Records: []string{"192.0.2.1"}, Line: LineDefault},
+		{Id: "dianxin-id", Name: "www.example.com.", Type: "A", Ttl: 300, Records: []string{"192.0.2.2"}, Line: "Dianxin"},
+	}
+
+	var updates []RecordSet
+	var created []RecordSet
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/zones":
+			_ = json.NewEncoder(w).Encode(ListZonesResponse{Zones: []Zone{{Id: "zone-id", Name: "example.com."}}})
+		case r.URL.Path == "/v2.1/zones/zone-id/recordsets" && r.Method == http.MethodGet:
+			var found []RecordSet
+			for _, rs := range existing {
+				if line := r.URL.Query().Get("line_id"); line == "" || line == rs.Line {
+					found = append(found, rs)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(ListRecordsResponse{RecordSets: found})
+		case r.URL.Path == "/v2.1/zones/zone-id/recordsets" && r.Method == http.MethodPost:
+			var rs RecordSet
+			_ = json.NewDecoder(r.Body).Decode(&rs)
+			rs.Id = "new-id"
+			created = append(created, rs)
+			_ = json.NewEncoder(w).Encode(rs)
+		case strings.HasPrefix(r.URL.Path, "/v2.1/zones/zone-id/recordsets/") && r.Method == http.MethodPut:
+			var rs RecordSet
+			_ = json.NewDecoder(r.Body).Decode(&rs)
+			updates = append(updates, rs)
+			_ = json.NewEncoder(w).Encode(rs)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p := &Provider{AccessKeyId: "ak", SecretAccessKey: "sk", Endpoint: server.URL, ResolutionLines: true}
+	ctx := context.Background()
+
+	records, err := p.GetRecords(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %+v", records)
+	}
+	if data, ok := recordData(records[1]); !ok || data.Line != "Dianxin" {
+		t.Errorf("expected the Dianxin line in ProviderData, got %+v", records[1])
+	}
+
+	// The Dianxin RRset is replaced and a weighted Yidong RRset is created,
+	// leaving the default line untouched.
+	_, err = p.SetRecords(ctx, "example.com.", []libdns.Record{
+		libdns.Address{Name: "www", TTL: 5 * time.Minute, IP: netip.MustParseAddr("192.0.2.3"), ProviderData: RecordData{Line: "Dianxin"}},
+		libdns.Address{Name: "www", TTL: 5 * time.Minute, IP: netip.MustParseAddr("192.0.2.4"), ProviderData: &RecordData{Line: "Yidong", Weight: &weight}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(updates) != 1 || updates[0].Id != "dianxin-id" || !sameValues("A", updates[0].Records, []string{"192.0.2.3"}) {
+		t.Errorf("unexpected updates %+v", updates)
+	}
+	if len(created) != 1 || created[0].Line != "Yidong" || created[0].Weight == nil || *created[0].Weight != weight {
+		t.Errorf("unexpected created recordsets %+v", created)
+	}
+
+	p.ResolutionLines = false
+	_, err = p.SetRecords(ctx, "example.com.", []libdns.Record{
+		libdns.Address{Name: "www", IP: netip.MustParseAddr("192.0.2.3"), ProviderData: RecordData{Line: "Dianxin"}},
+	})
+	if err == nil {
+		t.Error("expected an error for a resolution line without resolution_lines")
+	}
+}
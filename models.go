@@ -3,22 +3,123 @@ package huaweicloud
 import (
 	"time"
 
+	"github.com/libdns/huaweicloud/internal/dnsutil"
 	"github.com/libdns/libdns"
 )
 
 type ListZonesResponse struct {
-	Zones []Zone `json:"zones,omitempty"`
+	// 指向当前资源或者其他资源的链接。当查询需要分页时，需要提供self和next两个链接。
+	Links *Links `json:"links,omitempty"`
+	// 分页查询时，返回的资源数量信息。
+	Metadata *Metadata `json:"metadata,omitempty"`
+	Zones    []Zone    `json:"zones,omitempty"`
 }
 
 type ListRecordsResponse struct {
+	// 指向当前资源或者其他资源的链接。当查询需要分页时，需要提供self和next两个链接。
+	Links *Links `json:"links,omitempty"`
+	// 分页查询时，返回的资源数量信息。
+	Metadata   *Metadata   `json:"metadata,omitempty"`
 	RecordSets []RecordSet `json:"recordsets,omitempty"`
 }
 
+type Links struct {
+	// 当前资源的链接。
+	Self string `json:"self,omitempty"`
+	// 下一页资源的链接。
+	Next string `json:"next,omitempty"`
+}
+
+type Metadata struct {
+	// 满足查询条件的资源总数，不受分页（即limit、offset参数）影响。
+	TotalCount int32 `json:"total_count,omitempty"`
+}
+
 type Zone struct {
 	// zone的ID，uuid形式的一个资源标识。
 	Id string `json:"id,omitempty"`
 	// zone名称。
 	Name string `json:"name,omitempty"`
+	// zone类型，公网（public）或者内网（private）。
+	ZoneType string `json:"zone_type,omitempty"`
+	// 资源状态，取值为ACTIVE、PENDING_CREATE、PENDING_UPDATE、PENDING_DELETE、ERROR、FREEZE、DISABLE。
+	Status string `json:"status,omitempty"`
+	// 内网zone关联的Router（VPC）列表。
+	Routers []Router `json:"routers,omitempty"`
+	// 管理该zone的管理员邮箱，用于生成该zone的SOA记录。
+	Email string `json:"email,omitempty"`
+	// 用于填写默认生成的SOA记录中有效缓存时间，以秒为单位。
+	Ttl int32 `json:"ttl,omitempty"`
+	// 对zone的描述信息。
+	Description string `json:"description,omitempty"`
+	// 用于填写默认生成的SOA记录中的序列号，该值只读。
+	Serial int32 `json:"serial,omitempty"`
+	// 该zone下的recordset个数。
+	RecordNum int32 `json:"record_num,omitempty"`
+	// 托管该zone的pool，由系统分配。
+	PoolId string `json:"pool_id,omitempty"`
+	// 主从模式中，从DNS服务器获取DNS信息的地址。
+	Masters []string `json:"masters,omitempty"`
+}
+
+type CreateZoneRequest struct {
+	// 待创建的域名，可带或不带末尾的点。
+	Name string `json:"name"`
+	// zone类型，公网（public）或者内网（private），默认为public。
+	ZoneType string `json:"zone_type,omitempty"`
+	// 管理该zone的管理员邮箱。
+	Email string `json:"email,omitempty"`
+	// 用于填写默认生成的SOA记录中有效缓存时间，以秒为单位，默认为300。
+	Ttl int32 `json:"ttl,omitempty"`
+	// 对zone的描述信息。
+	Description string `json:"description,omitempty"`
+	// 内网zone关联的Router（VPC），创建内网zone时必填。
+	Router *Router `json:"router,omitempty"`
+}
+
+type UpdateZoneRequest struct {
+	// 管理该zone的管理员邮箱。
+	Email string `json:"email,omitempty"`
+	// 用于填写默认生成的SOA记录中有效缓存时间，以秒为单位。
+	Ttl int32 `json:"ttl,omitempty"`
+	// 对zone的描述信息。
+	Description string `json:"description,omitempty"`
+}
+
+type ListNameserversResponse struct {
+	// 名称服务器列表。
+	Nameservers []Nameserver `json:"nameservers,omitempty"`
+}
+
+type Nameserver struct {
+	// 主机名，公网zone返回该字段。
+	Hostname string `json:"hostname,omitempty"`
+	// 名称服务器地址，内网zone返回该字段。
+	Address string `json:"address,omitempty"`
+	// 优先级。
+	Priority int32 `json:"priority,omitempty"`
+}
+
+type Router struct {
+	// 关联VPC的ID。
+	RouterId string `json:"router_id,omitempty"`
+	// 关联VPC所在的region名称。
+	RouterRegion string `json:"router_region,omitempty"`
+	// 资源状态。
+	Status string `json:"status,omitempty"`
+}
+
+type routerRequest struct {
+	Router Router `json:"router"`
+}
+
+func (z Zone) hasRouter(routerId string) bool {
+	for _, r := range z.Routers {
+		if r.RouterId == routerId {
+			return true
+		}
+	}
+	return false
 }
 
 type RecordSet struct {
@@ -32,44 +133,79 @@ type RecordSet struct {
 	Ttl int32 `json:"ttl,omitempty"`
 	// 域名解析后的值。
 	Records []string `json:"records,omitempty"`
+	// 资源状态，取值为ACTIVE、PENDING_CREATE、PENDING_UPDATE、PENDING_DELETE、ERROR、DISABLE。
+	Status string `json:"status,omitempty"`
+	// 对域名的描述。
+	Description string `json:"description,omitempty"`
+	// 解析线路ID，仅v2.1接口支持，默认为default_view。
+	Line string `json:"line,omitempty"`
+	// 解析记录的权重，取值范围0~1000，仅v2.1接口支持。
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+type batchDeleteRequest struct {
+	// 待删除的记录集ID列表。
+	RecordsetIds []string `json:"recordset_ids"`
+}
+
+type batchUpdateRequest struct {
+	// 待修改的记录集列表。
+	RecordSets []RecordSet `json:"recordsets"`
+}
+
+type statusRequest struct {
+	// 待设置的记录集状态，取值为ENABLE或DISABLE。
+	Status string `json:"status"`
+}
+
+type batchStatusRequest struct {
+	// 待设置的记录集状态，取值为ENABLE或DISABLE。
+	Status string `json:"status"`
+	// 待设置状态的记录集ID列表。
+	RecordsetIds []string `json:"recordset_ids"`
 }
 
 func (r RecordSet) libdnsRecord(zone string) ([]libdns.Record, error) {
 	var records []libdns.Record
 	for _, record := range r.Records {
-		rr, err := libdns.RR{
+		rr, err := parseRecord(libdns.RR{
 			Name: libdns.RelativeName(r.Name, zone),
 			TTL:  time.Duration(r.Ttl) * time.Second,
 			Type: r.Type,
 			Data: record,
-		}.Parse()
+		})
 		if err != nil {
 			return nil, err
 		}
+		if r.Line != "" || r.Weight != nil || r.Status == StatusDisable {
+			rr = withRecordData(rr, RecordData{Line: r.Line, Weight: r.Weight, Disabled: r.Status == StatusDisable})
+		}
 		records = append(records, rr)
 	}
 	return records, nil
 }
 
+// fqdn returns the fully-qualified form of name within zone, always with
+// a trailing dot as Huawei Cloud returns it.
+func fqdn(name, zone string) string {
+	return dnsutil.Fqdn(libdns.AbsoluteName(name, zone))
+}
+
 func hwRecord(zone string, r libdns.Record) (RecordSet, error) {
-	rr := r.RR()
+	rr, err := hwRR(r)
+	if err != nil {
+		return RecordSet{}, err
+	}
 	if rr.TTL <= 0 {
 		rr.TTL = 1 * time.Second // 华为云支持最小 1 秒
 	}
-	// Fix TXT records missing starting quotation mark
-	// https://github.com/libdns/huaweicloud/issues/1
-	if rr.Type == "TXT" {
-		if rr.Data[0] != '"' {
-			rr.Data = `"` + rr.Data
-		}
-		if rr.Data[len(rr.Data)-1] != '"' {
-			rr.Data = rr.Data + `"`
-		}
-	}
+	data, _ := recordData(r)
 	return RecordSet{
-		Name:    libdns.AbsoluteName(rr.Name, zone),
+		Name:    fqdn(rr.Name, zone),
 		Type:    rr.Type,
 		Ttl:     int32(rr.TTL.Seconds()),
 		Records: []string{rr.Data},
+		Line:    data.Line,
+		Weight:  data.Weight,
 	}, nil
 }
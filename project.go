@@ -0,0 +1,107 @@
+package huaweicloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+)
+
+const (
+	// HeaderXProjectId selects the IAM project a request operates in.
+	HeaderXProjectId = "X-Project-Id"
+)
+
+type ListProjectsResponse struct {
+	Projects []Project `json:"projects,omitempty"`
+}
+
+type Project struct {
+	// 项目ID。
+	Id string `json:"id,omitempty"`
+	// 项目名称，如"cn-north-4"或子项目"cn-north-4_sub"。
+	Name string `json:"name,omitempty"`
+	// 项目所属账号ID。
+	DomainId string `json:"domain_id,omitempty"`
+}
+
+// WithProjectId makes every request operate in the given IAM project by
+// sending the X-Project-Id header.
+func WithProjectId(projectId string) ClientOption {
+	return func(c *Client) {
+		c.projectId = projectId
+	}
+}
+
+// WithProjectName makes every request operate in the IAM project with the
+// given name, such as "cn-north-4_sub". The project ID is looked up through
+// IAM on first use.
+func WithProjectName(projectName string) ClientOption {
+	return func(c *Client) {
+		c.projectName = projectName
+	}
+}
+
+// WithIAMEndpoint overrides the IAM endpoint used to look up projects, which
+// defaults to "https://iam.<region>.myhuaweicloud.com".
+func WithIAMEndpoint(endpoint string) ClientOption {
+	return func(c *Client) {
+		if endpoint != "" {
+			c.iamEndpoint = endpoint
+		}
+	}
+}
+
+// GetProjectId returns the ID of the project requests operate in, looking
+// it up by name if needed. It is empty when no project is configured, in
+// which case the default project of the region is used.
+func (c *Client) GetProjectId(ctx context.Context) (string, error) {
+	c.projectMu.Lock()
+	defer c.projectMu.Unlock()
+
+	if c.projectId != "" || c.projectName == "" {
+		return c.projectId, nil
+	}
+
+	projects, err := c.ListProjects(ctx, c.projectName)
+	if err != nil {
+		return "", err
+	}
+	for _, project := range projects {
+		if project.Name == c.projectName {
+			c.projectId = project.Id
+			return project.Id, nil
+		}
+	}
+	return "", &NotFoundError{Resource: "project", Name: c.projectName}
+}
+
+// ListProjects returns the IAM projects of the account, or only the one
+// with the given name if name is not empty.
+func (c *Client) ListProjects(ctx context.Context, name string) ([]Project, error) {
+	baseURL, err := parseEndpoint(c.iamEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	url := baseURL.JoinPath("v3", "projects")
+	if name != "" {
+		url.RawQuery = neturl.Values{"name": {name}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(ListProjectsResponse)
+	if err = c.doRequest(req, resp, false); err != nil {
+		return nil, fmt.Errorf("looking up project %q: %w", name, err)
+	}
+
+	return resp.Projects, nil
+}
+
+// defaultIAMEndpoint returns the IAM endpoint of the region.
+func defaultIAMEndpoint(region string) string {
+	return "https://iam." + region + ".myhuaweicloud.com"
+}
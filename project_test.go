@@ -0,0 +1,48 @@
+package huaweicloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientProjectName(t *testing.T) {
+	var lookups int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/projects":
+			lookups++
+			if r.Header.Get(HeaderXProjectId) != "" {
+				t.Errorf("expected project lookup without %s", HeaderXProjectId)
+			}
+			if name := r.URL.Query().Get("name"); name != "cn-north-4_sub" {
+				t.Errorf("unexpected project name %q", name)
+			}
+			_ = json.NewEncoder(w).Encode(ListProjectsResponse{Projects: []Project{{Id: "sub-project-id", Name: "cn-north-4_sub"}}})
+		case "/v2/zones":
+			if got := r.Header.Get(HeaderXProjectId); got != "sub-project-id" {
+				t.Errorf("expected %s sub-project-id, got %q", HeaderXProjectId, got)
+			}
+			if auth := r.Header.Get(HeaderXAuthorization); !strings.Contains(auth, "x-project-id") {
+				t.Errorf("expected %s to be signed, got %q", HeaderXProjectId, auth)
+			}
+			_ = json.NewEncoder(w).Encode(ListZonesResponse{})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("ak", "sk", "cn-north-4", WithEndpoint(server.URL), WithIAMEndpoint(server.URL), WithProjectName("cn-north-4_sub"))
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListZones(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected the project ID to be looked up once, got %d lookups", lookups)
+	}
+}
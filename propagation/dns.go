@@ -0,0 +1,345 @@
+package propagation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DNS record types the checker can compare.
+const (
+	typeA     uint16 = 1
+	typeNS    uint16 = 2
+	typeCNAME uint16 = 5
+	typeMX    uint16 = 15
+	typeTXT   uint16 = 16
+	typeAAAA  uint16 = 28
+
+	classIN uint16 = 1
+)
+
+var recordTypes = map[string]uint16{
+	"A":     typeA,
+	"NS":    typeNS,
+	"CNAME": typeCNAME,
+	"MX":    typeMX,
+	"TXT":   typeTXT,
+	"AAAA":  typeAAAA,
+}
+
+const (
+	headerLen = 12
+	// maxUDPSize is the largest response accepted over UDP.
+	maxUDPSize = 4096
+
+	flagResponse  = 1 << 15
+	flagTruncated = 1 << 9
+	rcodeMask     = 0xf
+
+	rcodeSuccess  = 0
+	rcodeNXDomain = 3
+)
+
+var errTruncated = errors.New("dns: message truncated")
+
+type question struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+}
+
+type resourceRecord struct {
+	name   string
+	rrtype uint16
+	class  uint16
+	ttl    uint32
+	// data is the record data in presentation format. TXT character-strings
+	// are concatenated.
+	data string
+}
+
+type message struct {
+	id        uint16
+	flags     uint16
+	questions []question
+	answers   []resourceRecord
+}
+
+func (m *message) rcode() int {
+	return int(m.flags & rcodeMask)
+}
+
+// newQuery builds a non-recursive query for name and qtype.
+func newQuery(name string, qtype uint16) (*message, []byte, error) {
+	var id [2]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, nil, err
+	}
+
+	q := &message{
+		id:        binary.BigEndian.Uint16(id[:]),
+		questions: []question{{name: name, qtype: qtype, qclass: classIN}},
+	}
+	b, err := q.pack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return q, b, nil
+}
+
+// pack encodes the header and questions of the message.
+func (m *message) pack() ([]byte, error) {
+	b := make([]byte, headerLen, 512)
+	binary.BigEndian.PutUint16(b[0:], m.id)
+	binary.BigEndian.PutUint16(b[2:], m.flags)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.questions)))
+
+	var err error
+	for _, q := range m.questions {
+		if b, err = appendName(b, q.name); err != nil {
+			return nil, err
+		}
+		b = appendUint16(b, q.qtype)
+		b = appendUint16(b, q.qclass)
+	}
+	return b, nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// appendName appends name in wire format, without compression.
+func appendName(b []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf("dns: invalid name %q", name)
+			}
+			b = append(append(b, byte(len(label))), label...)
+		}
+	}
+	return append(b, 0), nil
+}
+
+// parseMessage decodes the header, questions and answers of a message.
+// Authority and additional sections are ignored, and so is everything after
+// the header of a truncated message.
+func parseMessage(b []byte) (*message, error) {
+	if len(b) < headerLen {
+		return nil, errTruncated
+	}
+	m := &message{
+		id:    binary.BigEndian.Uint16(b[0:]),
+		flags: binary.BigEndian.Uint16(b[2:]),
+	}
+	if m.flags&flagTruncated != 0 {
+		return m, nil
+	}
+	qdcount := int(binary.BigEndian.Uint16(b[4:]))
+	ancount := int(binary.BigEndian.Uint16(b[6:]))
+
+	off := headerLen
+	for i := 0; i < qdcount; i++ {
+		name, n, err := readName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		off = n
+		if off+4 > len(b) {
+			return nil, errTruncated
+		}
+		m.questions = append(m.questions, question{
+			name:   name,
+			qtype:  binary.BigEndian.Uint16(b[off:]),
+			qclass: binary.BigEndian.Uint16(b[off+2:]),
+		})
+		off += 4
+	}
+
+	for i := 0; i < ancount; i++ {
+		name, n, err := readName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		off = n
+		if off+10 > len(b) {
+			return nil, errTruncated
+		}
+		rr := resourceRecord{
+			name:   name,
+			rrtype: binary.BigEndian.Uint16(b[off:]),
+			class:  binary.BigEndian.Uint16(b[off+2:]),
+			ttl:    binary.BigEndian.Uint32(b[off+4:]),
+		}
+		rdlen := int(binary.BigEndian.Uint16(b[off+8:]))
+		off += 10
+		if off+rdlen > len(b) {
+			return nil, errTruncated
+		}
+		if rr.data, err = readData(b, off, rdlen, rr.rrtype); err != nil {
+			return nil, err
+		}
+		off += rdlen
+		m.answers = append(m.answers, rr)
+	}
+
+	return m, nil
+}
+
+// readData returns the record data at b[off:off+length] in presentation
+// format. Types the checker does not compare are left empty.
+func readData(b []byte, off, length int, rrtype uint16) (string, error) {
+	rdata := b[off : off+length]
+	switch rrtype {
+	case typeA, typeAAAA:
+		if len(rdata) != net.IPv4len && len(rdata) != net.IPv6len {
+			return "", fmt.Errorf("dns: invalid address length %d", len(rdata))
+		}
+		return net.IP(rdata).String(), nil
+	case typeNS, typeCNAME:
+		name, _, err := readName(b, off)
+		return name, err
+	case typeMX:
+		if len(rdata) < 3 {
+			return "", errTruncated
+		}
+		name, _, err := readName(b, off+2)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(int(binary.BigEndian.Uint16(rdata))) + " " + name, nil
+	case typeTXT:
+		var sb strings.Builder
+		for i := 0; i < len(rdata); {
+			n := int(rdata[i])
+			if i+1+n > len(rdata) {
+				return "", errTruncated
+			}
+			sb.Write(rdata[i+1 : i+1+n])
+			i += 1 + n
+		}
+		return sb.String(), nil
+	default:
+		return "", nil
+	}
+}
+
+// readName decodes the possibly compressed name at b[off:], returning it
+// with a trailing dot and the offset following it.
+func readName(b []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(b) {
+			return "", 0, errTruncated
+		}
+		n := int(b[off])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case n&0xc0 == 0xc0:
+			if off+1 >= len(b) {
+				return "", 0, errTruncated
+			}
+			if jumps++; jumps > 32 {
+				return "", 0, errors.New("dns: too many compression pointers")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3fff)
+		case n&0xc0 != 0:
+			return "", 0, fmt.Errorf("dns: invalid label type %#x", n&0xc0)
+		default:
+			if off+1+n > len(b) {
+				return "", 0, errTruncated
+			}
+			labels = append(labels, string(b[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
+
+// exchange sends a query for name and qtype to addr over UDP, retrying over
+// TCP if the response is truncated.
+func exchange(ctx context.Context, addr, name string, qtype uint16) (*message, error) {
+	q, query, err := newQuery(name, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := exchangeUDP(ctx, addr, query)
+	if err == nil && resp.flags&flagTruncated != 0 {
+		resp, err = exchangeTCP(ctx, addr, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.id != q.id || resp.flags&flagResponse == 0 {
+		return nil, fmt.Errorf("dns: unexpected response from %s", addr)
+	}
+	if len(resp.questions) != 1 || !strings.EqualFold(resp.questions[0].name, q.questions[0].name) || resp.questions[0].qtype != qtype {
+		return nil, fmt.Errorf("dns: response from %s does not match the question", addr)
+	}
+	return resp, nil
+}
+
+func exchangeUDP(ctx context.Context, addr string, query []byte) (*message, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxUDPSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return parseMessage(buf[:n])
+}
+
+func exchangeTCP(ctx context.Context, addr string, query []byte) (*message, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	b := appendUint16(make([]byte, 0, 2+len(query)), uint16(len(query)))
+	if _, err := conn.Write(append(b, query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	return parseMessage(buf)
+}
@@ -0,0 +1,220 @@
+// Package propagation checks that records changed through the Huawei Cloud
+// DNS API are served by the authoritative nameservers of their zone, for
+// example before asking an ACME server to validate a DNS-01 challenge.
+package propagation
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/libdns/huaweicloud"
+	"github.com/libdns/libdns"
+)
+
+const (
+	defaultPort         = "53"
+	defaultInterval     = 2 * time.Second
+	defaultTimeout      = 2 * time.Minute
+	defaultQueryTimeout = 5 * time.Second
+)
+
+// NameserverLister looks up the nameservers of a zone. It is implemented by
+// both huaweicloud.Client and huaweicloud.Provider.
+type NameserverLister interface {
+	ListNameservers(ctx context.Context, zone string) ([]huaweicloud.Nameserver, error)
+}
+
+// Checker queries the authoritative nameservers of a zone directly, without
+// recursion, until they serve the expected records.
+type Checker struct {
+	// Nameservers looks up the nameservers of the zone.
+	Nameservers NameserverLister
+	// Port is optional and defaults to 53. Nameserver hostnames that already
+	// include a port are queried on that port.
+	Port string
+	// Interval is optional and sets the delay between rounds of queries,
+	// defaulting to 2 seconds.
+	Interval time.Duration
+	// Timeout is optional and bounds how long Wait waits, defaulting to 2
+	// minutes. The deadline of the context passed to Wait also applies.
+	Timeout time.Duration
+	// QueryTimeout is optional and bounds a single query, defaulting to 5
+	// seconds.
+	QueryTimeout time.Duration
+}
+
+// rrset is the expected values of a name and type.
+type rrset struct {
+	name   string
+	rrtype string
+	qtype  uint16
+	values []string
+}
+
+// Wait blocks until every nameserver of the zone answers with all of the
+// given records. Other values in the same RRset are ignored, so the records
+// returned by AppendRecords can be passed as is. Supported types are A,
+// AAAA, CNAME, MX, NS and TXT.
+func (c *Checker) Wait(ctx context.Context, zone string, records []libdns.Record) error {
+	expected, err := expectedRRsets(zone, records)
+	if err != nil {
+		return err
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	nameservers, err := c.Nameservers.ListNameservers(ctx, zone)
+	if err != nil {
+		return fmt.Errorf("propagation: listing nameservers of %s: %w", zone, err)
+	}
+	if len(nameservers) == 0 {
+		return fmt.Errorf("propagation: zone %s has no nameservers", zone)
+	}
+
+	pending := make(map[string][]rrset, len(nameservers))
+	for _, ns := range nameservers {
+		pending[c.address(ns)] = expected
+	}
+
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	lastErr := make(map[string]error)
+	for {
+		for addr, rrsets := range pending {
+			var remaining []rrset
+			for _, rrs := range rrsets {
+				if err := c.check(ctx, addr, rrs); err != nil {
+					lastErr[addr] = err
+					remaining = append(remaining, rrs)
+				}
+			}
+			if len(remaining) == 0 {
+				delete(pending, addr)
+			} else {
+				pending[addr] = remaining
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			var reasons []string
+			for addr := range pending {
+				reasons = append(reasons, fmt.Sprintf("%s: %v", addr, lastErr[addr]))
+			}
+			return fmt.Errorf("propagation: %d of %d nameservers do not serve the records yet: %s: %w",
+				len(pending), len(nameservers), strings.Join(reasons, "; "), ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// check queries the nameserver at addr and reports why its answer does not
+// include the expected values, if it does not.
+func (c *Checker) check(ctx context.Context, addr string, rrs rrset) error {
+	queryTimeout := c.QueryTimeout
+	if queryTimeout <= 0 {
+		queryTimeout = defaultQueryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	resp, err := exchange(ctx, addr, rrs.name, rrs.qtype)
+	if err != nil {
+		return err
+	}
+	switch resp.rcode() {
+	case rcodeSuccess:
+	case rcodeNXDomain:
+		return fmt.Errorf("%s does not exist", rrs.name)
+	default:
+		return fmt.Errorf("querying %s %s: rcode %d", rrs.name, rrs.rrtype, resp.rcode())
+	}
+
+	served := make(map[string]bool)
+	for _, rr := range resp.answers {
+		if rr.rrtype == rrs.qtype && strings.EqualFold(rr.name, rrs.name) {
+			served[normalize(rr.rrtype, rr.data)] = true
+		}
+	}
+	for _, value := range rrs.values {
+		if !served[value] {
+			return fmt.Errorf("%s %s %q is not served", rrs.name, rrs.rrtype, value)
+		}
+	}
+	return nil
+}
+
+// address returns the host and port a nameserver is queried on.
+func (c *Checker) address(ns huaweicloud.Nameserver) string {
+	host := ns.Hostname
+	if host == "" {
+		host = ns.Address
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+
+	port := c.Port
+	if port == "" {
+		port = defaultPort
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// expectedRRsets groups the records by name and type.
+func expectedRRsets(zone string, records []libdns.Record) ([]rrset, error) {
+	zone = strings.TrimSuffix(zone, ".") + "."
+
+	var result []rrset
+	index := make(map[string]int)
+	for _, record := range records {
+		rr := record.RR()
+		rrtype := strings.ToUpper(rr.Type)
+		qtype, ok := recordTypes[rrtype]
+		if !ok {
+			return nil, fmt.Errorf("propagation: unsupported record type %q", rr.Type)
+		}
+
+		name := strings.ToLower(libdns.AbsoluteName(rr.Name, zone))
+		key := name + " " + rrtype
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			result = append(result, rrset{name: name, rrtype: rrtype, qtype: qtype})
+		}
+		result[i].values = append(result[i].values, normalize(qtype, rr.Data))
+	}
+	return result, nil
+}
+
+// normalize returns the value in the form answers are compared in.
+func normalize(qtype uint16, value string) string {
+	switch qtype {
+	case typeA, typeAAAA:
+		if ip := net.ParseIP(value); ip != nil {
+			return ip.String()
+		}
+		return value
+	case typeNS, typeCNAME, typeMX:
+		return strings.TrimSuffix(strings.ToLower(value), ".") + "."
+	default:
+		return value
+	}
+}
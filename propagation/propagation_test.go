@@ -0,0 +1,271 @@
+package propagation
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/huaweicloud"
+	"github.com/libdns/libdns"
+)
+
+// testServer is an authoritative DNS server on localhost serving TXT, A and
+// AAAA records over UDP and TCP.
+type testServer struct {
+	addr string
+	// truncate makes every UDP response truncated, forcing TCP.
+	truncate bool
+
+	mu      sync.Mutex
+	records map[string][]string
+	queries int
+}
+
+func newTestServer(t *testing.T, truncate bool) *testServer {
+	t.Helper()
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	if err != nil {
+		udp.Close()
+		t.Skipf("cannot listen on TCP and UDP on the same port: %v", err)
+	}
+	t.Cleanup(func() {
+		udp.Close()
+		tcp.Close()
+	})
+
+	s := &testServer{addr: udp.LocalAddr().String(), truncate: truncate, records: make(map[string][]string)}
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := s.handle(buf[:n], true); resp != nil {
+				_, _ = udp.WriteTo(resp, addr)
+			}
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err != nil {
+					return
+				}
+				if resp := s.handle(query, false); resp != nil {
+					_, _ = conn.Write(append(appendUint16(nil, uint16(len(resp))), resp...))
+				}
+			}()
+		}
+	}()
+	return s
+}
+
+func (s *testServer) set(name, rrtype string, values ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[strings.ToLower(name)+" "+rrtype] = values
+}
+
+func (s *testServer) handle(query []byte, udp bool) []byte {
+	q, err := parseMessage(query)
+	if err != nil || len(q.questions) != 1 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+
+	question := q.questions[0]
+	resp := &message{id: q.id, flags: flagResponse, questions: q.questions}
+	if udp && s.truncate {
+		resp.flags |= flagTruncated
+		b, _ := resp.pack()
+		return b
+	}
+
+	var rrtype string
+	for name, qtype := range recordTypes {
+		if qtype == question.qtype {
+			rrtype = name
+		}
+	}
+	values, ok := s.records[strings.ToLower(question.name)+" "+rrtype]
+	if !ok {
+		resp.flags |= rcodeNXDomain
+	}
+	b, err := resp.pack()
+	if err != nil {
+		return nil
+	}
+	binary.BigEndian.PutUint16(b[6:], uint16(len(values)))
+	for _, value := range values {
+		// Point to the name in the question section.
+		b = appendUint16(b, 0xc000|headerLen)
+		b = appendUint16(b, question.qtype)
+		b = appendUint16(b, classIN)
+		b = append(b, 0, 0, 1, 44)
+		var rdata []byte
+		switch question.qtype {
+		case typeTXT:
+			for len(value) > 255 {
+				rdata = append(append(rdata, 255), value[:255]...)
+				value = value[255:]
+			}
+			rdata = append(append(rdata, byte(len(value))), value...)
+		case typeA:
+			rdata = net.ParseIP(value).To4()
+		case typeAAAA:
+			rdata = net.ParseIP(value).To16()
+		}
+		b = appendUint16(b, uint16(len(rdata)))
+		b = append(b, rdata...)
+	}
+	return b
+}
+
+type nameservers []huaweicloud.Nameserver
+
+func (n nameservers) ListNameservers(ctx context.Context, zone string) ([]huaweicloud.Nameserver, error) {
+	return n, nil
+}
+
+func TestCheckerWait(t *testing.T) {
+	long := strings.Repeat("x", 300)
+
+	tests := []struct {
+		name    string
+		records []libdns.Record
+		// served is what the second server serves once propagated; the first
+		// server always serves it.
+		served map[string][]string
+		err    bool
+	}{
+		{
+			name:    "txt",
+			records: []libdns.Record{libdns.TXT{Name: "_acme-challenge", Text: "token"}},
+			served:  map[string][]string{"_acme-challenge.example.com. TXT": {"other", "token"}},
+		},
+		{
+			name:    "long txt",
+			records: []libdns.Record{libdns.TXT{Name: "_acme-challenge", Text: long}},
+			served:  map[string][]string{"_acme-challenge.example.com. TXT": {long}},
+		},
+		{
+			name: "addresses",
+			records: []libdns.Record{
+				libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"},
+				libdns.RR{Name: "www", Type: "AAAA", Data: "2001:db8::1"},
+			},
+			served: map[string][]string{
+				"www.example.com. A":    {"192.0.2.1"},
+				"www.example.com. AAAA": {"2001:0db8::1"},
+			},
+		},
+		{
+			name:    "stale value",
+			records: []libdns.Record{libdns.TXT{Name: "_acme-challenge", Text: "token"}},
+			served:  map[string][]string{"_acme-challenge.example.com. TXT": {"old"}},
+			err:     true,
+		},
+		{
+			name:    "missing name",
+			records: []libdns.Record{libdns.TXT{Name: "_acme-challenge", Text: "token"}},
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := newTestServer(t, false), newTestServer(t, true)
+			for key, values := range tt.served {
+				name, rrtype, _ := strings.Cut(key, " ")
+				first.set(name, rrtype, values...)
+			}
+			// The second server only serves the records from its third query.
+			go func() {
+				for {
+					second.mu.Lock()
+					queries := second.queries
+					second.mu.Unlock()
+					if queries >= 2 {
+						break
+					}
+					time.Sleep(time.Millisecond)
+				}
+				for key, values := range tt.served {
+					name, rrtype, _ := strings.Cut(key, " ")
+					second.set(name, rrtype, values...)
+				}
+			}()
+
+			checker := &Checker{
+				Nameservers: nameservers{{Hostname: first.addr}, {Address: second.addr}},
+				Interval:    10 * time.Millisecond,
+				Timeout:     500 * time.Millisecond,
+			}
+			err := checker.Wait(context.Background(), "example.com.", tt.records)
+			if tt.err {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("expected deadline exceeded, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckerUnsupportedType(t *testing.T) {
+	checker := &Checker{Nameservers: nameservers{{Hostname: "127.0.0.1"}}}
+	err := checker.Wait(context.Background(), "example.com.", []libdns.Record{
+		libdns.RR{Name: "@", Type: "SOA", Data: "ns1.example.com. admin.example.com. 1 7200 900 1209600 300"},
+	})
+	if err == nil {
+		t.Fatal("expected an error for an unsupported type")
+	}
+}
+
+func TestReadNameCompression(t *testing.T) {
+	b, _ := appendName(make([]byte, headerLen), "example.com.")
+	// "www" followed by a pointer to "example.com." at the header length.
+	b = append(append(b, 3, 'w', 'w', 'w'), 0xc0, headerLen)
+
+	name, off, err := readName(b, headerLen+13)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "www.example.com." || off != len(b) {
+		t.Errorf("expected www.example.com. ending at %d, got %s ending at %d", len(b), name, off)
+	}
+
+	// A pointer to itself must not loop forever.
+	loop := append(make([]byte, headerLen), 0xc0, headerLen)
+	if _, _, err := readName(loop, headerLen); err == nil {
+		t.Error("expected an error for a compression loop")
+	}
+}
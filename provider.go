@@ -2,29 +2,114 @@ package huaweicloud
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/libdns/libdns"
 )
 
 // Provider facilitates DNS record manipulation with Huawei Cloud
 type Provider struct {
-	// AccessKeyId is required by the Huawei Cloud API for authentication.
+	// AccessKeyId is used by the Huawei Cloud API for authentication. When it is
+	// empty, credentials are read from the environment (HUAWEICLOUD_SDK_AK,
+	// HUAWEICLOUD_SDK_SK and HUAWEICLOUD_SDK_SECURITY_TOKEN), the hcloud
+	// configuration file, or the ECS metadata service, in this order.
 	AccessKeyId string `json:"access_key_id,omitempty"`
-	// SecretAccessKey is required by the Huawei Cloud API for authentication.
+	// SecretAccessKey is used by the Huawei Cloud API for authentication.
 	SecretAccessKey string `json:"secret_access_key,omitempty"`
-	// RegionId is optional and defaults to "cn-south-1".
+	// SecurityToken is optional and required only for temporary credentials.
+	SecurityToken string `json:"security_token,omitempty"`
+	// Credentials is optional and overrides how credentials are obtained.
+	Credentials CredentialsProvider `json:"-"`
+	// IAMDomainName, IAMUserName and IAMPassword are optional and select IAM
+	// token authentication instead of AK/SK signing. The token is obtained
+	// from IAM and renewed before it expires.
+	IAMDomainName string `json:"iam_domain_name,omitempty"`
+	IAMUserName   string `json:"iam_user_name,omitempty"`
+	IAMPassword   string `json:"iam_password,omitempty"`
+	// AuthToken is optional and authenticates with a pre-issued IAM token,
+	// such as a federated token, instead of AK/SK signing.
+	AuthToken string `json:"auth_token,omitempty"`
+	// IAMEndpoint is optional and overrides the IAM endpoint, which defaults
+	// to "https://iam.<region>.myhuaweicloud.com".
+	IAMEndpoint string `json:"iam_endpoint,omitempty"`
+	// ProjectId is optional and selects the IAM project, such as a
+	// sub-project, that requests operate in. It defaults to the default
+	// project of the region.
+	ProjectId string `json:"project_id,omitempty"`
+	// ProjectName is optional and selects the IAM project by name, such as
+	// "cn-north-4_sub". The project ID is looked up through IAM.
+	ProjectName string `json:"project_name,omitempty"`
+	// RegionId is optional and defaults to HUAWEICLOUD_REGION, then "cn-south-1".
 	RegionId string `json:"region_id,omitempty"`
-	// once is used to ensure the client is initialized only once.
-	once sync.Once
+	// ZoneCacheTTL is optional and controls how long zone IDs are cached,
+	// defaulting to 5 minutes. A negative value disables the cache.
+	ZoneCacheTTL time.Duration `json:"zone_cache_ttl,omitempty"`
+	// ZoneType is optional and selects "public" (default), "private" or
+	// "auto" zones. With "auto", a name matching both a public and a private
+	// zone is rejected as ambiguous.
+	ZoneType string `json:"zone_type,omitempty"`
+	// VpcId is optional and restricts private zones to those associated
+	// with the given VPC.
+	VpcId string `json:"vpc_id,omitempty"`
+	// ResolutionLines is optional and manages records through the v2.1 API,
+	// which supports resolution lines and weights for public zones. Records
+	// then carry a RecordData in their ProviderData field, and RRsets are
+	// grouped by name, type and line.
+	ResolutionLines bool `json:"resolution_lines,omitempty"`
+	// Retry is optional and overrides the default policy for retrying
+	// throttled and failed API requests. Fields left unset keep their
+	// default value.
+	Retry *RetryPolicy `json:"retry,omitempty"`
+	// Endpoint is optional and overrides the DNS API endpoint, which defaults
+	// to "https://dns.<region>.myhuaweicloud.com".
+	Endpoint string `json:"endpoint,omitempty"`
+	// UserAgent is optional and overrides the User-Agent header.
+	UserAgent string `json:"user_agent,omitempty"`
+	// HTTPClient is optional and overrides the HTTP client used for requests.
+	HTTPClient *http.Client `json:"-"`
+	// WaitForActive is optional and makes AppendRecords, SetRecords and
+	// DeleteRecords block until every changed recordset has left its
+	// PENDING_* status. A recordset ending in ERROR is reported as a
+	// *ResourceStatusError.
+	WaitForActive bool `json:"wait_for_active,omitempty"`
+	// WaitInterval is optional and sets how often recordsets are polled
+	// while waiting, defaulting to 2 seconds.
+	WaitInterval time.Duration `json:"wait_interval,omitempty"`
+	// WaitTimeout is optional and bounds how long a call waits, defaulting
+	// to 2 minutes.
+	WaitTimeout time.Duration `json:"wait_timeout,omitempty"`
+	// ExcludeDisabled is optional and makes GetRecords skip the records of
+	// disabled recordsets, which are otherwise returned with a RecordData
+	// whose Disabled field is true in their ProviderData.
+	ExcludeDisabled bool `json:"exclude_disabled,omitempty"`
+	// BatchThreshold is optional and sets the number of recordset updates or
+	// deletions in one call from which the batch endpoints are used,
+	// defaulting to 5. A negative value disables batching. Failed batch
+	// requests fall back to one request per recordset.
+	BatchThreshold int `json:"batch_threshold,omitempty"`
+	// mu guards client and clientKey.
+	mu sync.Mutex
 	//  client is the Huawei Cloud DNS client.
 	client *Client
+	// clientKey identifies the configuration client was built from, so that
+	// the client is rebuilt when the configuration changes.
+	clientKey string
 }
 
 // GetRecords lists all the records in the zone.
 func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
-	client := p.getClient()
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
 
 	records, err := client.GetRecords(ctx, zone)
 	if err != nil {
@@ -33,6 +118,9 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 
 	var results []libdns.Record
 	for _, record := range records {
+		if p.ExcludeDisabled && record.Status == StatusDisable {
+			continue
+		}
 		rec, err := record.libdnsRecord(zone)
 		if err != nil {
 			return nil, fmt.Errorf("parsing Huawei Cloud DNS record %+v: %v", record, err)
@@ -44,113 +132,466 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 }
 
 // AppendRecords adds records to the zone. It returns the records that were added.
+// Values are merged into the existing recordset of their (name, type) pair, if any;
+// values that are already present are left untouched and not returned.
+// If some recordsets cannot be changed, the records of the others are returned along with a *BatchError.
 // NOTE: This implementation is NOT atomic.
 func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	client := p.getClient()
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
 
-	var results []libdns.Record
-	for _, rec := range records {
-		hwRec, err := hwRecord(zone, rec)
-		if err != nil {
-			return nil, fmt.Errorf("parsing libdns record %+v: %v", rec, err)
-		}
-		resp, err := client.AppendRecord(ctx, zone, hwRec)
+	rrsets, err := p.groupRRsets(zone, records)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []recordChange
+	var added []RecordSet
+	for _, rrset := range rrsets {
+		existing, err := client.getRecordSet(ctx, zone, rrset.Name, rrset.Type, rrset.Line)
 		if err != nil {
 			return nil, err
 		}
-		libdnsRecs, err := resp.libdnsRecord(zone)
+
+		if existing == nil {
+			changes = append(changes, recordChange{rs: rrset, create: true})
+			added = append(added, rrset)
+			continue
+		}
+		update := *existing
+		var values []string
+		update.Records, values = unionValues(rrset.Type, append([]string(nil), existing.Records...), rrset.Records)
+		if len(values) == 0 {
+			continue
+		}
+		changes = append(changes, recordChange{rs: update})
+		rrset.Records, rrset.Ttl = values, existing.Ttl
+		added = append(added, rrset)
+	}
+
+	var results []libdns.Record
+	var settled []change
+	outcomes := p.applyChanges(ctx, client, zone, changes)
+	for i, outcome := range outcomes {
+		if outcome.Err != nil {
+			continue
+		}
+		settled = append(settled, change{id: outcome.RecordSet.Id})
+
+		libdnsRecs, err := added[i].libdnsRecord(zone)
 		if err != nil {
-			return nil, fmt.Errorf("parsing Huawei Cloud DNS record %+v: %v", resp, err)
+			return nil, fmt.Errorf("parsing Huawei Cloud DNS record %+v: %v", added[i], err)
 		}
 		results = append(results, libdnsRecs...)
 	}
+	if err := batchError(outcomes); err != nil {
+		return results, err
+	}
+
+	if err := p.waitForActive(ctx, client, zone, settled); err != nil {
+		return nil, err
+	}
 
 	return results, nil
 }
 
 // SetRecords sets the records in the zone, either by updating existing records or creating new ones.
-// It returns the updated records.
+// Records are grouped into RRsets by (name, type), and each RRset in the input replaces the existing
+// one with exactly one create or update call. It returns the updated records.
+// If some recordsets cannot be changed, the records of the others are returned along with a *BatchError.
 // NOTE: This implementation is NOT atomic.
 func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	client := p.getClient()
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	rrsets, err := p.groupRRsets(zone, records)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []recordChange
+	var unchanged []RecordSet
+	for _, rrset := range rrsets {
+		existing, err := client.getRecordSet(ctx, zone, rrset.Name, rrset.Type, rrset.Line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case existing == nil:
+			changes = append(changes, recordChange{rs: rrset, create: true})
+		case existing.Ttl == rrset.Ttl && sameWeight(existing.Weight, rrset.Weight) && sameValues(rrset.Type, existing.Records, rrset.Records):
+			unchanged = append(unchanged, *existing)
+		default:
+			rrset.Id = existing.Id
+			changes = append(changes, recordChange{rs: rrset})
+		}
+	}
+
+	var settled []change
+	outcomes := p.applyChanges(ctx, client, zone, changes)
+	for _, outcome := range outcomes {
+		if outcome.Err == nil {
+			settled = append(settled, change{id: outcome.RecordSet.Id})
+			unchanged = append(unchanged, outcome.RecordSet)
+		}
+	}
 
 	var results []libdns.Record
-	for _, record := range records {
-		rr := record.RR()
-		id, err := client.GetRecordId(ctx, zone, rr.Name, rr.Type, rr.Data)
+	for _, rs := range unchanged {
+		libdnsRecs, err := rs.libdnsRecord(zone)
 		if err != nil {
-			// No existing record found, create a new one
-			hwRec, err := hwRecord(zone, record)
-			if err != nil {
-				return nil, fmt.Errorf("parsing libdns record %+v: %v", record, err)
-			}
-			resp, err := client.AppendRecord(ctx, zone, hwRec)
-			if err != nil {
-				return nil, err
-			}
-			libdnsRecs, err := resp.libdnsRecord(zone)
-			if err != nil {
-				return nil, fmt.Errorf("parsing Huawei Cloud DNS record %+v: %v", resp, err)
-			}
-			results = append(results, libdnsRecs...)
-		} else {
-			// Existing record found, update it
-			hwRec, err := hwRecord(zone, record)
-			if err != nil {
-				return nil, fmt.Errorf("parsing libdns record %+v: %v", record, err)
-			}
-			hwRec.Id = id
-			hwRec.Ttl = int32(rr.TTL.Seconds())
-			resp, err := client.UpdateRecord(ctx, zone, hwRec)
-			if err != nil {
-				return nil, err
-			}
-			libdnsRecs, err := resp.libdnsRecord(zone)
-			if err != nil {
-				return nil, fmt.Errorf("parsing Huawei Cloud DNS record %+v: %v", resp, err)
-			}
-			results = append(results, libdnsRecs...)
+			return nil, fmt.Errorf("parsing Huawei Cloud DNS record %+v: %v", rs, err)
 		}
+		results = append(results, libdnsRecs...)
+	}
+	if err := batchError(outcomes); err != nil {
+		return results, err
+	}
+
+	if err := p.waitForActive(ctx, client, zone, settled); err != nil {
+		return nil, err
 	}
 
 	return results, nil
 }
 
 // DeleteRecords deletes the records from the zone. It returns the records that were deleted.
+// Only matching values are removed: a recordset is updated when other values remain and deleted
+// once it becomes empty. Empty type, TTL or data fields in the input match any value, and records
+// that do not exist are silently ignored.
+// If some recordsets cannot be changed, the records of the others are returned along with a *BatchError.
 // NOTE: This implementation is NOT atomic.
 func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	client := p.getClient()
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
 
-	var results []libdns.Record
+	if err := checkTypes(records); err != nil {
+		return nil, err
+	}
+
+	// Collect the remaining and removed values of every affected recordset
+	// first, so that each one is updated or deleted exactly once.
+	var order []string
+	remaining := make(map[string]*RecordSet)
+	removed := make(map[string][]string)
+	lookups := make(map[rrsetKey][]RecordSet)
 	for _, record := range records {
 		rr := record.RR()
-		id, err := client.GetRecordId(ctx, zone, rr.Name, rr.Type, rr.Data)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get record ID for %s: %v", rr.Name, err)
+		if rr.Name == "" {
+			return nil, fmt.Errorf("deleting record %+v: name is required", rr)
 		}
-		resp, err := client.DeleteRecord(ctx, zone, id)
-		if err != nil {
-			return nil, fmt.Errorf("failed to delete record %s: %v", rr.Name, err)
+		data, _ := recordData(record)
+		if data.Line != "" && !p.ResolutionLines {
+			return nil, fmt.Errorf("deleting record %+v: resolution line %q requires resolution_lines", rr, data.Line)
 		}
-		libdnsRecs, err := resp.libdnsRecord(zone)
+
+		key := rrsetKey{Name: strings.ToLower(fqdn(rr.Name, zone)), Type: strings.ToUpper(rr.Type), Line: data.Line}
+		recordSets, ok := lookups[key]
+		if !ok {
+			var err error
+			recordSets, err = client.FindRecordSetsByLine(ctx, zone, rr.Name, rr.Type, data.Line)
+			if err != nil {
+				return nil, err
+			}
+			lookups[key] = recordSets
+		}
+
+		for _, rs := range recordSets {
+			if rr.TTL != 0 && int32(rr.TTL.Seconds()) != rs.Ttl {
+				continue
+			}
+			current, ok := remaining[rs.Id]
+			if !ok {
+				rs := rs
+				rs.Records = append([]string(nil), rs.Records...)
+				current = &rs
+			}
+
+			// Without data, every value of the recordset is deleted.
+			var match string
+			if rr.Data != "" {
+				key, ok, err := matchValue(zone, rr, rs)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue
+				}
+				match = key
+			}
+
+			var kept []string
+			for _, value := range current.Records {
+				if rr.Data == "" || valueKey(rs.Type, value) == match {
+					removed[rs.Id] = append(removed[rs.Id], value)
+				} else {
+					kept = append(kept, value)
+				}
+			}
+			if len(kept) == len(current.Records) {
+				continue
+			}
+			current.Records = kept
+			if !ok {
+				remaining[rs.Id] = current
+				order = append(order, rs.Id)
+			}
+		}
+	}
+
+	changes := make([]recordChange, 0, len(order))
+	for _, id := range order {
+		rs := remaining[id]
+		changes = append(changes, recordChange{rs: *rs, deleted: len(rs.Records) == 0})
+	}
+
+	var results []libdns.Record
+	var settled []change
+	outcomes := p.applyChanges(ctx, client, zone, changes)
+	for i, outcome := range outcomes {
+		if outcome.Err != nil {
+			continue
+		}
+		rs := changes[i].rs
+		settled = append(settled, change{id: rs.Id, deleted: outcome.Deleted})
+
+		deleted := RecordSet{Name: rs.Name, Type: rs.Type, Ttl: rs.Ttl, Records: removed[rs.Id], Line: rs.Line, Weight: rs.Weight}
+		libdnsRecs, err := deleted.libdnsRecord(zone)
 		if err != nil {
-			return nil, fmt.Errorf("parsing Huawei Cloud DNS record %+v: %v", resp, err)
+			return nil, fmt.Errorf("parsing Huawei Cloud DNS record %+v: %v", deleted, err)
 		}
 		results = append(results, libdnsRecs...)
 	}
+	if err := batchError(outcomes); err != nil {
+		return results, err
+	}
+
+	if err := p.waitForActive(ctx, client, zone, settled); err != nil {
+		return nil, err
+	}
 
 	return results, nil
 }
 
-// getClient initializes the client for the provider.
-func (p *Provider) getClient() *Client {
-	p.once.Do(func() {
-		if p.AccessKeyId == "" || p.SecretAccessKey == "" {
-			panic("huaweicloud: credentials missing")
+// ListZones lists all the zones of the account.
+func (p *Provider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	zones, err := client.ListZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]libdns.Zone, 0, len(zones))
+	for _, zone := range zones {
+		results = append(results, libdns.Zone{
+			Name: strings.TrimSuffix(zone.Name, ".") + ".",
+		})
+	}
+
+	return results, nil
+}
+
+// groupRRsets groups the records into RRsets, rejecting resolution lines
+// and weights unless they are enabled.
+func (p *Provider) groupRRsets(zone string, records []libdns.Record) ([]RecordSet, error) {
+	rrsets, err := groupRRsets(zone, records)
+	if err != nil {
+		return nil, err
+	}
+	if !p.ResolutionLines {
+		for _, rrset := range rrsets {
+			if rrset.Line != "" || rrset.Weight != nil {
+				return nil, fmt.Errorf("%s %s: resolution lines and weights require resolution_lines", rrset.Name, rrset.Type)
+			}
 		}
-		p.client = NewClient(p.AccessKeyId, p.SecretAccessKey, p.RegionId)
+	}
+	return rrsets, nil
+}
+
+// ListNameservers returns the nameservers serving the zone.
+func (p *Provider) ListNameservers(ctx context.Context, zone string) ([]Nameserver, error) {
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ListNameservers(ctx, zone)
+}
+
+// Validate checks the configuration of the provider without making any
+// API calls. It is also called by every libdns method.
+func (p *Provider) Validate() error {
+	if (p.AccessKeyId == "") != (p.SecretAccessKey == "") {
+		return fmt.Errorf("huaweicloud: access_key_id and secret_access_key must be set together")
+	}
+	if p.SecurityToken != "" && p.AccessKeyId == "" {
+		return fmt.Errorf("huaweicloud: security_token requires access_key_id and secret_access_key")
+	}
+	password := p.IAMDomainName != "" || p.IAMUserName != "" || p.IAMPassword != ""
+	if password && (p.IAMDomainName == "" || p.IAMUserName == "" || p.IAMPassword == "") {
+		return fmt.Errorf("huaweicloud: iam_domain_name, iam_user_name and iam_password must be set together")
+	}
+	if password && p.AuthToken != "" {
+		return fmt.Errorf("huaweicloud: auth_token and iam_password are mutually exclusive")
+	}
+	if (password || p.AuthToken != "") && (p.AccessKeyId != "" || p.Credentials != nil) {
+		return fmt.Errorf("huaweicloud: IAM token authentication and AK/SK credentials are mutually exclusive")
+	}
+	if p.ProjectId != "" && p.ProjectName != "" {
+		return fmt.Errorf("huaweicloud: project_id and project_name are mutually exclusive")
+	}
+	if p.RegionId != "" && !regionPattern.MatchString(p.RegionId) {
+		return fmt.Errorf("huaweicloud: invalid region_id %q, expected a region such as \"cn-south-1\"", p.RegionId)
+	}
+	for _, endpoint := range []string{p.Endpoint, p.IAMEndpoint} {
+		if endpoint == "" {
+			continue
+		}
+		if _, err := parseEndpoint(endpoint); err != nil {
+			return fmt.Errorf("huaweicloud: %v", err)
+		}
+	}
+	if _, err := zoneTypes(p.ZoneType); err != nil {
+		return fmt.Errorf("huaweicloud: %v", err)
+	}
+	if p.ResolutionLines && p.ZoneType != "" && p.ZoneType != ZoneTypePublic {
+		return fmt.Errorf("huaweicloud: resolution_lines is only supported for public zones")
+	}
+	if p.Retry != nil {
+		if p.Retry.MaxAttempts < 0 || p.Retry.BaseDelay < 0 || p.Retry.MaxDelay < 0 {
+			return fmt.Errorf("huaweicloud: retry settings must not be negative")
+		}
+		if jitter := p.Retry.Jitter; jitter != nil && (*jitter < 0 || *jitter > 1) {
+			return fmt.Errorf("huaweicloud: retry jitter must be between 0 and 1, got %v", *jitter)
+		}
+	}
+	return nil
+}
+
+// regionPattern matches region IDs such as "cn-south-1" or "ap-southeast-3".
+var regionPattern = regexp.MustCompile(`^[a-z]{2,}(-[a-z0-9]+)+$`)
+
+// getClient returns the client for the provider, building it on first use
+// and again whenever the configuration has changed.
+func (p *Provider) getClient() (*Client, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	key, err := p.configKey()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client != nil && p.clientKey == key {
+		return p.client, nil
+	}
+
+	region := p.RegionId
+	if region == "" {
+		region = os.Getenv(EnvRegion)
+	}
+	if region == "" {
+		region = defaultRegion
+	}
+	opts := []ClientOption{
+		WithZoneCacheTTL(p.ZoneCacheTTL),
+		WithZoneType(p.ZoneType),
+		WithVpcId(p.VpcId),
+		WithEndpoint(p.Endpoint),
+		WithUserAgent(p.UserAgent),
+		WithHTTPClient(p.HTTPClient),
+		WithIAMEndpoint(p.IAMEndpoint),
+		WithProjectId(p.ProjectId),
+		WithProjectName(p.ProjectName),
+	}
+	if p.Retry != nil {
+		opts = append(opts, WithRetryPolicy(p.Retry.withDefaults()))
+	}
+	if p.ResolutionLines {
+		opts = append(opts, WithResolutionLines())
+	}
+	if p.AuthToken != "" || p.IAMUserName != "" {
+		iamEndpoint := p.IAMEndpoint
+		if iamEndpoint == "" {
+			iamEndpoint = defaultIAMEndpoint(region)
+		}
+		projectName := p.ProjectName
+		if projectName == "" {
+			projectName = region
+		}
+		opts = append(opts, WithAuthenticator(&TokenAuthenticator{
+			Token:       p.AuthToken,
+			Endpoint:    iamEndpoint,
+			DomainName:  p.IAMDomainName,
+			UserName:    p.IAMUserName,
+			Password:    p.IAMPassword,
+			ProjectId:   p.ProjectId,
+			ProjectName: projectName,
+			HTTPClient:  p.HTTPClient,
+		}))
+	} else {
+		credentials := p.Credentials
+		if credentials == nil {
+			credentials = DefaultCredentialsChain(StaticCredentials{
+				AccessKeyId:     p.AccessKeyId,
+				SecretAccessKey: p.SecretAccessKey,
+				SecurityToken:   p.SecurityToken,
+			})
+		}
+		opts = append(opts, WithCredentialsProvider(credentials))
+	}
+	client := NewClient(p.AccessKeyId, p.SecretAccessKey, region, opts...)
+	if client.err != nil {
+		return nil, fmt.Errorf("huaweicloud: %v", client.err)
+	}
+
+	p.client, p.clientKey = client, key
+	return client, nil
+}
+
+// configKey serializes the fields of the provider that configure its
+// client. Fields that cannot be serialized are identified by their address.
+// Settings only read by the provider itself, such as WaitForActive, do not
+// cause the client to be rebuilt.
+func (p *Provider) configKey() (string, error) {
+	config, err := json.Marshal([]any{
+		p.AccessKeyId, p.SecretAccessKey, p.SecurityToken,
+		p.IAMDomainName, p.IAMUserName, p.IAMPassword, p.AuthToken, p.IAMEndpoint,
+		p.ProjectId, p.ProjectName, p.RegionId,
+		p.ZoneCacheTTL, p.ZoneType, p.VpcId, p.ResolutionLines, p.Retry,
+		p.Endpoint, p.UserAgent,
 	})
-	return p.client
+	if err != nil {
+		return "", fmt.Errorf("huaweicloud: %v", err)
+	}
+	return fmt.Sprintf("%s|%s|%s|%s", config, identity(p.HTTPClient), identity(p.Credentials), os.Getenv(EnvRegion)), nil
+}
+
+// identity describes v by address if it is a reference type, or by value
+// otherwise.
+func identity(v any) string {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return fmt.Sprintf("%T@%x", v, rv.Pointer())
+	default:
+		return fmt.Sprintf("%#v", v)
+	}
 }
 
 // Interface guards
@@ -159,4 +600,5 @@ var (
 	_ libdns.RecordAppender = (*Provider)(nil)
 	_ libdns.RecordSetter   = (*Provider)(nil)
 	_ libdns.RecordDeleter  = (*Provider)(nil)
+	_ libdns.ZoneLister     = (*Provider)(nil)
 )
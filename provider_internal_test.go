@@ -0,0 +1,116 @@
+package huaweicloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProviderValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider *Provider
+		valid    bool
+	}{
+		{name: "inline credentials", provider: &Provider{AccessKeyId: "ak", SecretAccessKey: "sk", RegionId: "cn-north-4"}, valid: true},
+		{name: "credentials chain", provider: &Provider{}, valid: true},
+		{name: "missing secret", provider: &Provider{AccessKeyId: "ak"}},
+		{name: "token without keys", provider: &Provider{SecurityToken: "token"}},
+		{name: "invalid region", provider: &Provider{AccessKeyId: "ak", SecretAccessKey: "sk", RegionId: "South China"}},
+		{name: "invalid endpoint", provider: &Provider{AccessKeyId: "ak", SecretAccessKey: "sk", Endpoint: "dns.example.com"}},
+		{name: "invalid zone type", provider: &Provider{AccessKeyId: "ak", SecretAccessKey: "sk", ZoneType: "internal"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.provider.Validate()
+			if (err == nil) != tt.valid {
+				t.Errorf("expected valid %v, got %v", tt.valid, err)
+			}
+			if _, clientErr := tt.provider.getClient(); (clientErr == nil) != tt.valid {
+				t.Errorf("expected getClient to agree with Validate, got %v", clientErr)
+			}
+		})
+	}
+}
+
+func TestProviderRebuildsClient(t *testing.T) {
+	provider := &Provider{AccessKeyId: "ak"}
+	if _, err := provider.GetRecords(context.Background(), "example.com."); err == nil {
+		t.Fatal("expected configuration error")
+	}
+
+	provider.SecretAccessKey = "sk"
+	first, err := provider.getClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, _ := provider.getClient(); again != first {
+		t.Errorf("expected client to be reused while the configuration is unchanged")
+	}
+
+	provider.RegionId = "ap-southeast-1"
+	rebuilt, err := provider.getClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rebuilt == first || rebuilt.region != "ap-southeast-1" {
+		t.Errorf("expected client to be rebuilt for the new region")
+	}
+
+	// Settings only read by the provider keep the client and its zone cache.
+	provider.WaitForActive = true
+	provider.WaitTimeout = time.Minute
+	provider.ExcludeDisabled = true
+	provider.BatchThreshold = 10
+	if again, _ := provider.getClient(); again != rebuilt {
+		t.Errorf("expected client to be reused when only provider settings change")
+	}
+}
+
+func TestProviderPartialRetryPolicy(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var retry RetryPolicy
+	if err := json.Unmarshal([]byte(`{"max_attempts":3,"base_delay":1000000}`), &retry); err != nil {
+		t.Fatal(err)
+	}
+	p := &Provider{AccessKeyId: "ak", SecretAccessKey: "sk", Endpoint: server.URL, Retry: &retry}
+	client, err := p.getClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defaults := DefaultRetryPolicy()
+	if policy := client.retryPolicy; policy.MaxAttempts != 3 || policy.BaseDelay != time.Millisecond ||
+		policy.MaxDelay != defaults.MaxDelay || !reflect.DeepEqual(policy.RetryStatuses, defaults.RetryStatuses) {
+		t.Errorf("expected the unset fields to keep their defaults, got %+v", policy)
+	}
+	if jitter := client.retryPolicy.Jitter; jitter == nil || *jitter != *defaults.Jitter {
+		t.Errorf("expected the default jitter, got %v", jitter)
+	}
+
+	// An explicit zero jitter turns jitter off.
+	var noJitter RetryPolicy
+	if err := json.Unmarshal([]byte(`{"jitter":0}`), &noJitter); err != nil {
+		t.Fatal(err)
+	}
+	if jitter := noJitter.withDefaults().Jitter; jitter == nil || *jitter != 0 {
+		t.Errorf("expected jitter to stay off, got %v", jitter)
+	}
+
+	if _, err := p.ListZones(context.Background()); !isStatus(err, http.StatusServiceUnavailable) {
+		t.Fatalf("expected the 503 to be returned, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected the 503 to be attempted 3 times, got %d", attempts)
+	}
+}
@@ -1,62 +1,396 @@
-package huaweicloud
+package huaweicloud_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/libdns/huaweicloud"
+	"github.com/libdns/huaweicloud/huaweicloudtest"
 	"github.com/libdns/libdns"
 )
 
+const zone = "example.com."
+
+// existing are the recordsets the zone starts with in every test.
+var existing = []huaweicloud.RecordSet{
+	{Name: "www.example.com.", Type: "A", Ttl: 300, Records: []string{"192.0.2.1", "192.0.2.2"}},
+	{Name: "example.com.", Type: "TXT", Ttl: 600, Records: []string{`"hello"`}},
+}
+
 func TestProvider(t *testing.T) {
-	provider := Provider{
-		AccessKeyId:     "",
-		SecretAccessKey: "",
+	tests := []struct {
+		name string
+		// setup prepares the server and the provider.
+		setup func(*huaweicloudtest.Server, *huaweicloud.Provider)
+		call  func(context.Context, *huaweicloud.Provider) ([]libdns.Record, error)
+		// want are the returned records, and state the recordsets of the
+		// zone afterwards, both as sorted strings.
+		want    []string
+		state   []string
+		wantErr func(error) bool
+	}{
+		{
+			name: "get records",
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.GetRecords(ctx, zone)
+			},
+			want: []string{"@ 600 TXT hello", "www 300 A 192.0.2.1", "www 300 A 192.0.2.2"},
+		},
+		{
+			name: "get records across pages",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				s.SetPageSize(1)
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.GetRecords(ctx, zone)
+			},
+			want: []string{"@ 600 TXT hello", "www 300 A 192.0.2.1", "www 300 A 192.0.2.2"},
+		},
+		{
+			name: "list zones across pages",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				s.AddZone(huaweicloud.Zone{Name: "example.net."})
+				s.AddZone(huaweicloud.Zone{Name: "example.org."})
+				s.SetPageSize(1)
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				zones, err := p.ListZones(ctx)
+				// Zones are compared as records named after them.
+				var records []libdns.Record
+				for _, z := range zones {
+					records = append(records, libdns.RR{Name: z.Name})
+				}
+				return records, err
+			},
+			want: []string{"example.com. 0  ", "example.net. 0  ", "example.org. 0  "},
+		},
+		{
+			name: "append new rrset",
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.AppendRecords(ctx, zone, []libdns.Record{
+					libdns.RR{Name: "api", TTL: time.Minute, Type: "A", Data: "192.0.2.10"},
+					libdns.RR{Name: "api", TTL: time.Minute, Type: "A", Data: "192.0.2.11"},
+				})
+			},
+			want: []string{"api 60 A 192.0.2.10", "api 60 A 192.0.2.11"},
+			state: []string{
+				"api.example.com. A 60 192.0.2.10 192.0.2.11",
+				"example.com. TXT 600 \"hello\"",
+				"www.example.com. A 300 192.0.2.1 192.0.2.2",
+			},
+		},
+		{
+			name: "append merges into existing rrset",
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.AppendRecords(ctx, zone, []libdns.Record{
+					libdns.RR{Name: "www", TTL: time.Hour, Type: "A", Data: "192.0.2.2"},
+					libdns.RR{Name: "www", TTL: time.Hour, Type: "A", Data: "192.0.2.3"},
+				})
+			},
+			want: []string{"www 300 A 192.0.2.3"},
+			state: []string{
+				"example.com. TXT 600 \"hello\"",
+				"www.example.com. A 300 192.0.2.1 192.0.2.2 192.0.2.3",
+			},
+		},
+		{
+			name: "append existing value",
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.AppendRecords(ctx, zone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"}})
+			},
+			state: []string{
+				"example.com. TXT 600 \"hello\"",
+				"www.example.com. A 300 192.0.2.1 192.0.2.2",
+			},
+		},
+		{
+			name: "append existing text split differently",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				s.AddRecordSet(zone, huaweicloud.RecordSet{Name: "dkim.example.com.", Type: "TXT", Ttl: 300, Records: []string{`"ab" "cd"`}})
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.AppendRecords(ctx, zone, []libdns.Record{libdns.TXT{Name: "dkim", Text: "abcd"}})
+			},
+			state: []string{
+				"dkim.example.com. TXT 300 \"ab\" \"cd\"",
+				"example.com. TXT 600 \"hello\"",
+				"www.example.com. A 300 192.0.2.1 192.0.2.2",
+			},
+		},
+		{
+			name: "set replaces rrset",
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.SetRecords(ctx, zone, []libdns.Record{
+					libdns.RR{Name: "www.example.com.", TTL: time.Hour, Type: "A", Data: "192.0.2.9"},
+					libdns.RR{Name: "mail", TTL: time.Hour, Type: "A", Data: "192.0.2.25"},
+				})
+			},
+			want: []string{"mail 3600 A 192.0.2.25", "www 3600 A 192.0.2.9"},
+			state: []string{
+				"example.com. TXT 600 \"hello\"",
+				"mail.example.com. A 3600 192.0.2.25",
+				"www.example.com. A 3600 192.0.2.9",
+			},
+		},
+		{
+			name: "set unchanged rrset",
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.SetRecords(ctx, zone, []libdns.Record{libdns.TXT{Name: "@", TTL: 10 * time.Minute, Text: "hello"}})
+			},
+			want: []string{"@ 600 TXT hello"},
+			state: []string{
+				"example.com. TXT 600 \"hello\"",
+				"www.example.com. A 300 192.0.2.1 192.0.2.2",
+			},
+		},
+		{
+			name: "set unchanged text split differently",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				s.AddRecordSet(zone, huaweicloud.RecordSet{Name: "dkim.example.com.", Type: "TXT", Ttl: 300, Records: []string{`"ab" "cd"`}})
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.SetRecords(ctx, zone, []libdns.Record{libdns.TXT{Name: "dkim", TTL: 5 * time.Minute, Text: "abcd"}})
+			},
+			want: []string{"dkim 300 TXT abcd"},
+			state: []string{
+				"dkim.example.com. TXT 300 \"ab\" \"cd\"",
+				"example.com. TXT 600 \"hello\"",
+				"www.example.com. A 300 192.0.2.1 192.0.2.2",
+			},
+		},
+		{
+			name: "delete one value",
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.DeleteRecords(ctx, zone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"}})
+			},
+			want: []string{"www 300 A 192.0.2.1"},
+			state: []string{
+				"example.com. TXT 600 \"hello\"",
+				"www.example.com. A 300 192.0.2.2",
+			},
+		},
+		{
+			name: "delete value of any type",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				s.AddRecordSet(zone, huaweicloud.RecordSet{Name: "www.example.com.", Type: "MX", Ttl: 300, Records: []string{"10 mail.example.com."}})
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.DeleteRecords(ctx, zone, []libdns.Record{libdns.RR{Name: "www", Data: "192.0.2.1"}})
+			},
+			want: []string{"www 300 A 192.0.2.1"},
+			state: []string{
+				"example.com. TXT 600 \"hello\"",
+				"www.example.com. A 300 192.0.2.2",
+				"www.example.com. MX 300 10 mail.example.com.",
+			},
+		},
+		{
+			name: "delete records as returned",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				// Written by other tools, with values laid out differently
+				// than the provider would write them.
+				s.AddRecordSet(zone, huaweicloud.RecordSet{Name: "dkim.example.com.", Type: "TXT", Ttl: 300, Records: []string{`"ab" "cd"`}})
+				s.AddRecordSet(zone, huaweicloud.RecordSet{Name: "dkim.example.com.", Type: "CAA", Ttl: 300, Records: []string{"0 issue ca.example.net"}})
+				s.AddRecordSet(zone, huaweicloud.RecordSet{Name: "dkim.example.com.", Type: "MX", Ttl: 300, Records: []string{"10 Mail.Example.com."}})
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				records, err := p.GetRecords(ctx, zone)
+				if err != nil {
+					return nil, err
+				}
+				// The MX target is given in another case.
+				deleted := []libdns.Record{libdns.MX{Name: "dkim", Preference: 10, Target: "mail.example.com"}}
+				for _, record := range records {
+					if rr := record.RR(); rr.Name == "dkim" && rr.Type != "MX" {
+						deleted = append(deleted, record)
+					}
+				}
+				return p.DeleteRecords(ctx, zone, deleted)
+			},
+			want: []string{"dkim 300 CAA 0 issue \"ca.example.net\"", "dkim 300 MX 10 Mail.Example.com.", "dkim 300 TXT abcd"},
+			state: []string{
+				"example.com. TXT 600 \"hello\"",
+				"www.example.com. A 300 192.0.2.1 192.0.2.2",
+			},
+		},
+		{
+			name: "delete invalid value of the given type",
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.DeleteRecords(ctx, zone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "not an address"}})
+			},
+			wantErr: func(err error) bool {
+				return err != nil
+			},
+		},
+		{
+			name: "delete with wildcards",
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.DeleteRecords(ctx, zone, []libdns.Record{libdns.RR{Name: "www"}, libdns.RR{Name: "missing", Type: "A"}})
+			},
+			want:  []string{"www 300 A 192.0.2.1", "www 300 A 192.0.2.2"},
+			state: []string{"example.com. TXT 600 \"hello\""},
+		},
+		{
+			name: "throttled request is retried",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				s.Inject(huaweicloudtest.Fault{Method: http.MethodPost, StatusCode: http.StatusTooManyRequests, Code: "APIGW.0308", RetryAfter: "0", Count: 2})
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.AppendRecords(ctx, zone, []libdns.Record{libdns.RR{Name: "api", Type: "AAAA", Data: "2001:db8::1"}})
+			},
+			want: []string{"api 1 AAAA 2001:db8::1"},
+			state: []string{
+				"api.example.com. AAAA 1 2001:db8::1",
+				"example.com. TXT 600 \"hello\"",
+				"www.example.com. A 300 192.0.2.1 192.0.2.2",
+			},
+		},
+		{
+			name: "server errors exhaust retries",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				s.Inject(huaweicloudtest.Fault{Method: http.MethodPut, StatusCode: http.StatusInternalServerError, Code: "DNS.0500"})
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.SetRecords(ctx, zone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.9"}})
+			},
+			wantErr: func(err error) bool {
+				var batchErr *huaweicloud.BatchError
+				var apiErr *huaweicloud.APIError
+				return errors.As(err, &batchErr) && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusInternalServerError
+			},
+		},
+		{
+			name: "wait for active",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				s.SetPending(2, "")
+				p.WaitForActive = true
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.DeleteRecords(ctx, zone, []libdns.Record{libdns.RR{Name: "@", Type: "TXT"}})
+			},
+			want:  []string{"@ 600 TXT hello"},
+			state: []string{"www.example.com. A 300 192.0.2.1 192.0.2.2"},
+		},
+		{
+			name: "wait for failed change",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				s.SetPending(1, huaweicloud.StatusError)
+				p.WaitForActive = true
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.AppendRecords(ctx, zone, []libdns.Record{libdns.RR{Name: "api", Type: "A", Data: "192.0.2.10"}})
+			},
+			wantErr: func(err error) bool {
+				var statusErr *huaweicloud.ResourceStatusError
+				return errors.As(err, &statusErr) && statusErr.Status == huaweicloud.StatusError
+			},
+		},
+		{
+			name: "wrong credentials",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				p.SecretAccessKey = "wrong"
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.GetRecords(ctx, zone)
+			},
+			wantErr: huaweicloud.IsAuth,
+		},
+		{
+			name: "missing zone",
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				return p.GetRecords(ctx, "example.org.")
+			},
+			wantErr: huaweicloud.IsNotFound,
+		},
+		{
+			name: "slow server",
+			setup: func(s *huaweicloudtest.Server, p *huaweicloud.Provider) {
+				s.Inject(huaweicloudtest.Fault{Latency: time.Second})
+			},
+			call: func(ctx context.Context, p *huaweicloud.Provider) ([]libdns.Record, error) {
+				ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+				defer cancel()
+				return p.GetRecords(ctx, zone)
+			},
+			wantErr: func(err error) bool {
+				return errors.Is(err, context.DeadlineExceeded)
+			},
+		},
 	}
 
-	recs, err := provider.GetRecords(context.Background(), "")
-	if err != nil {
-		t.Fatalf("failed to get records: %v", err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := huaweicloudtest.NewServer(map[string]string{"ak": "sk"})
+			defer server.Close()
+			server.AddZone(huaweicloud.Zone{Name: zone})
+			for _, rs := range existing {
+				server.AddRecordSet(zone, rs)
+			}
+
+			retry := huaweicloud.DefaultRetryPolicy()
+			retry.BaseDelay = time.Millisecond
+			provider := &huaweicloud.Provider{
+				AccessKeyId:     "ak",
+				SecretAccessKey: "sk",
+				Endpoint:        server.URL,
+				Retry:           &retry,
+				WaitInterval:    time.Millisecond,
+			}
+			if tt.setup != nil {
+				tt.setup(server, provider)
+			}
+
+			records, err := tt.call(context.Background(), provider)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := formatRecords(records); fmt.Sprint(got) != fmt.Sprint(sorted(tt.want)) {
+				t.Errorf("expected records %q, got %q", tt.want, got)
+			}
+			if tt.state != nil {
+				if got := formatRecordSets(server.RecordSets(zone)); fmt.Sprint(got) != fmt.Sprint(sorted(tt.state)) {
+					t.Errorf("expected recordsets %q, got %q", tt.state, got)
+				}
+			}
+		})
 	}
-	fmt.Println(recs)
-
-	recs, err = provider.AppendRecords(context.Background(), "", []libdns.Record{
-		libdns.RR{
-			Name: "sub",
-			TTL:  10 * time.Minute,
-			Type: "A",
-			Data: "8.8.8.8",
-		},
-	})
-	if err != nil {
-		t.Fatalf("failed to append records: %v", err)
+}
+
+func formatRecords(records []libdns.Record) []string {
+	result := []string{}
+	for _, record := range records {
+		rr := record.RR()
+		result = append(result, fmt.Sprintf("%s %d %s %s", rr.Name, int(rr.TTL.Seconds()), rr.Type, rr.Data))
 	}
-	fmt.Println(recs)
-
-	recs, err = provider.SetRecords(context.Background(), "", []libdns.Record{
-		libdns.RR{
-			Name: "sub",
-			TTL:  10 * time.Minute,
-			Type: "A",
-			Data: "8.8.8.8",
-		},
-	})
-	if err != nil {
-		t.Fatalf("failed to set records: %v", err)
+	return sorted(result)
+}
+
+func formatRecordSets(recordSets []huaweicloud.RecordSet) []string {
+	result := []string{}
+	for _, rs := range recordSets {
+		values := sorted(append([]string(nil), rs.Records...))
+		result = append(result, fmt.Sprintf("%s %s %d %s", rs.Name, rs.Type, rs.Ttl, strings.Join(values, " ")))
 	}
-	fmt.Println(recs)
-
-	recs, err = provider.DeleteRecords(context.Background(), "", []libdns.Record{
-		libdns.RR{
-			Name: "sub",
-			TTL:  10 * time.Minute,
-			Type: "A",
-			Data: "8.8.8.8",
-		},
-	})
-	if err != nil {
-		t.Fatalf("failed to delete records: %v", err)
+	return sorted(result)
+}
+
+func sorted(values []string) []string {
+	if values == nil {
+		values = []string{}
 	}
-	fmt.Println(recs)
+	sort.Strings(values)
+	return values
 }
@@ -0,0 +1,179 @@
+package huaweicloud
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/libdns/huaweicloud/internal/dnsutil"
+	"github.com/libdns/libdns"
+)
+
+// checkType returns an *UnsupportedTypeError if Huawei Cloud DNS does not
+// support the record type. An empty type is accepted, as it matches every
+// type when deleting records.
+func checkType(typ string) error {
+	if typ != "" && !dnsutil.SupportedTypes[strings.ToUpper(typ)] {
+		return &UnsupportedTypeError{Type: typ}
+	}
+	return nil
+}
+
+// checkTypes checks the types of all records, so that an unsupported one
+// fails the call before any request is made.
+func checkTypes(records []libdns.Record) error {
+	for _, r := range records {
+		if err := checkType(r.RR().Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hwRR converts a libdns record into the name, type and value layout of
+// Huawei Cloud, which always has fully-qualified targets.
+func hwRR(r libdns.Record) (libdns.RR, error) {
+	switch rec := r.(type) {
+	case libdns.Address:
+		return rec.RR(), nil
+	case libdns.CNAME:
+		rr := rec.RR()
+		rr.Data = absoluteTarget(rec.Target)
+		return rr, nil
+	case libdns.NS:
+		rr := rec.RR()
+		rr.Data = absoluteTarget(rec.Target)
+		return rr, nil
+	case libdns.MX:
+		rr := rec.RR()
+		rr.Data = fmt.Sprintf("%d %s", rec.Preference, absoluteTarget(rec.Target))
+		return rr, nil
+	case libdns.SRV:
+		rr := rec.RR()
+		rr.Data = fmt.Sprintf("%d %d %d %s", rec.Priority, rec.Weight, rec.Port, absoluteTarget(rec.Target))
+		return rr, nil
+	case libdns.CAA:
+		rr := rec.RR()
+		rr.Data = fmt.Sprintf("%d %s %s", rec.Flags, rec.Tag, quoteCAA(rec.Value))
+		return rr, nil
+	case libdns.ServiceBinding:
+		rec.Target = absoluteTarget(rec.Target)
+		rr := rec.RR()
+		// The parameters are omitted in alias mode, leaving a trailing space.
+		rr.Data = strings.TrimSpace(rr.Data)
+		return rr, nil
+	case libdns.TXT:
+		rr := rec.RR()
+		rr.Data = encodeTXT(rec.Text)
+		return rr, nil
+	}
+
+	rr := r.RR()
+	if err := checkType(rr.Type); err != nil {
+		return libdns.RR{}, err
+	}
+	parsed, err := rr.Parse()
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	if _, ok := parsed.(libdns.RR); ok {
+		// A supported type libdns has no structure for, such as PTR.
+		return rr, nil
+	}
+	return hwRR(parsed)
+}
+
+// parseRecord converts a value as returned by Huawei Cloud into the typed
+// libdns record.
+func parseRecord(rr libdns.RR) (libdns.Record, error) {
+	switch rr.Type {
+	case "A", "AAAA":
+		ip, err := netip.ParseAddr(rr.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %v", rr.Type, rr.Data, err)
+		}
+		return libdns.Address{Name: rr.Name, TTL: rr.TTL, IP: ip}, nil
+	case "CNAME":
+		return libdns.CNAME{Name: rr.Name, TTL: rr.TTL, Target: rr.Data}, nil
+	case "NS":
+		return libdns.NS{Name: rr.Name, TTL: rr.TTL, Target: rr.Data}, nil
+	case "MX":
+		fields := strings.Fields(rr.Data)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid MX value %q", rr.Data)
+		}
+		preference, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MX preference in %q: %v", rr.Data, err)
+		}
+		return libdns.MX{Name: rr.Name, TTL: rr.TTL, Preference: uint16(preference), Target: fields[1]}, nil
+	case "SRV":
+		fields := strings.Fields(rr.Data)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid SRV value %q", rr.Data)
+		}
+		var numbers [3]uint16
+		for i := range numbers {
+			n, err := strconv.ParseUint(fields[i], 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SRV value %q: %v", rr.Data, err)
+			}
+			numbers[i] = uint16(n)
+		}
+		srv := libdns.SRV{Name: rr.Name, TTL: rr.TTL, Priority: numbers[0], Weight: numbers[1], Port: numbers[2], Target: fields[3]}
+		labels := strings.SplitN(rr.Name, ".", 3)
+		if len(labels) >= 2 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_") {
+			srv.Service = strings.TrimPrefix(labels[0], "_")
+			srv.Transport = strings.TrimPrefix(labels[1], "_")
+			srv.Name = "@"
+			if len(labels) == 3 {
+				srv.Name = labels[2]
+			}
+		}
+		return srv, nil
+	case "CAA":
+		fields := strings.SplitN(rr.Data, " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid CAA value %q", rr.Data)
+		}
+		flags, err := strconv.ParseUint(fields[0], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CAA flags in %q: %v", rr.Data, err)
+		}
+		return libdns.CAA{Name: rr.Name, TTL: rr.TTL, Flags: uint8(flags), Tag: fields[1], Value: unquoteCAA(fields[2])}, nil
+	case "HTTPS", "SVCB":
+		// The scheme and port are encoded in the name and the parameters
+		// have their own syntax, both of which libdns parses.
+		return rr.Parse()
+	case "TXT":
+		text, err := decodeTXT(rr.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TXT value %q: %v", rr.Data, err)
+		}
+		return libdns.TXT{Name: rr.Name, TTL: rr.TTL, Text: text}, nil
+	}
+	return rr, nil
+}
+
+// absoluteTarget returns the fully-qualified form of a target hostname,
+// which libdns leaves to the caller.
+func absoluteTarget(target string) string {
+	if target == "" || strings.HasSuffix(target, ".") {
+		return target
+	}
+	return target + "."
+}
+
+// quoteCAA quotes the value of a CAA record.
+func quoteCAA(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// unquoteCAA returns the value of a CAA record, which may be quoted.
+func unquoteCAA(value string) string {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return value
+	}
+	return strings.NewReplacer(`\\`, `\`, `\"`, `"`).Replace(value[1 : len(value)-1])
+}
@@ -0,0 +1,204 @@
+package huaweicloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestRecordConversion(t *testing.T) {
+	tests := []struct {
+		name   string
+		record libdns.Record
+		// hwName, hwType and hwValue are the recordset layout sent to and
+		// returned by Huawei Cloud.
+		hwName  string
+		hwType  string
+		hwValue string
+		// parsed is the record read back, if it differs from record.
+		parsed libdns.Record
+	}{
+		{
+			name:    "A",
+			record:  libdns.Address{Name: "www", TTL: time.Minute, IP: netip.MustParseAddr("192.0.2.1")},
+			hwName:  "www",
+			hwType:  "A",
+			hwValue: "192.0.2.1",
+		},
+		{
+			name:    "AAAA",
+			record:  libdns.Address{Name: "www", TTL: time.Minute, IP: netip.MustParseAddr("2001:db8::1")},
+			hwName:  "www",
+			hwType:  "AAAA",
+			hwValue: "2001:db8::1",
+		},
+		{
+			name:    "CNAME without trailing dot",
+			record:  libdns.CNAME{Name: "www", TTL: time.Minute, Target: "example.net"},
+			hwName:  "www",
+			hwType:  "CNAME",
+			hwValue: "example.net.",
+			parsed:  libdns.CNAME{Name: "www", TTL: time.Minute, Target: "example.net."},
+		},
+		{
+			name:    "NS",
+			record:  libdns.NS{Name: "sub", TTL: time.Minute, Target: "ns1.example.net."},
+			hwName:  "sub",
+			hwType:  "NS",
+			hwValue: "ns1.example.net.",
+		},
+		{
+			name:    "MX",
+			record:  libdns.MX{Name: "@", TTL: time.Minute, Preference: 10, Target: "mail.example.com"},
+			hwName:  "@",
+			hwType:  "MX",
+			hwValue: "10 mail.example.com.",
+			parsed:  libdns.MX{Name: "@", TTL: time.Minute, Preference: 10, Target: "mail.example.com."},
+		},
+		{
+			name:    "SRV",
+			record:  libdns.SRV{Service: "sip", Transport: "tcp", Name: "@", TTL: time.Minute, Priority: 10, Weight: 5, Port: 5060, Target: "sip.example.com."},
+			hwName:  "_sip._tcp",
+			hwType:  "SRV",
+			hwValue: "10 5 5060 sip.example.com.",
+		},
+		{
+			name:    "SRV below a name",
+			record:  libdns.SRV{Service: "xmpp", Transport: "udp", Name: "chat", TTL: time.Minute, Priority: 0, Weight: 0, Port: 5222, Target: "."},
+			hwName:  "_xmpp._udp.chat",
+			hwType:  "SRV",
+			hwValue: "0 0 5222 .",
+		},
+		{
+			name:    "CAA",
+			record:  libdns.CAA{Name: "@", TTL: time.Minute, Flags: 128, Tag: "iodef", Value: `mailto:"ca"@example.com`},
+			hwName:  "@",
+			hwType:  "CAA",
+			hwValue: `128 iodef "mailto:\"ca\"@example.com"`,
+		},
+		{
+			name:    "HTTPS alias mode",
+			record:  libdns.ServiceBinding{Scheme: "https", Name: "@", TTL: time.Minute, Priority: 0, Target: "cdn.example.net"},
+			hwName:  "@",
+			hwType:  "HTTPS",
+			hwValue: "0 cdn.example.net.",
+			parsed:  libdns.ServiceBinding{Scheme: "https", Name: "@", TTL: time.Minute, Priority: 0, Target: "cdn.example.net.", Params: libdns.SvcParams{}},
+		},
+		{
+			name:    "SVCB",
+			record:  libdns.ServiceBinding{Scheme: "dns", URLSchemePort: 853, Name: "resolver", TTL: time.Minute, Priority: 1, Target: "dns.example.com.", Params: libdns.SvcParams{"alpn": {"dot"}}},
+			hwName:  "_853._dns.resolver",
+			hwType:  "SVCB",
+			hwValue: "1 dns.example.com. alpn=dot",
+		},
+		{
+			name:    "TXT",
+			record:  libdns.TXT{Name: "@", TTL: time.Minute, Text: "hello"},
+			hwName:  "@",
+			hwType:  "TXT",
+			hwValue: `"hello"`,
+		},
+		{
+			name:    "empty TXT",
+			record:  libdns.TXT{Name: "@", TTL: time.Minute},
+			hwName:  "@",
+			hwType:  "TXT",
+			hwValue: `""`,
+		},
+		{
+			name:    "TXT RR without data",
+			record:  libdns.RR{Name: "@", TTL: time.Minute, Type: "TXT"},
+			hwName:  "@",
+			hwType:  "TXT",
+			hwValue: `""`,
+			parsed:  libdns.TXT{Name: "@", TTL: time.Minute},
+		},
+		{
+			name:    "generic RR",
+			record:  libdns.RR{Name: "@", TTL: time.Minute, Type: "MX", Data: "20 backup.example.com"},
+			hwName:  "@",
+			hwType:  "MX",
+			hwValue: "20 backup.example.com.",
+			parsed:  libdns.MX{Name: "@", TTL: time.Minute, Preference: 20, Target: "backup.example.com."},
+		},
+		{
+			name:    "PTR",
+			record:  libdns.RR{Name: "1", TTL: time.Minute, Type: "PTR", Data: "www.example.com."},
+			hwName:  "1",
+			hwType:  "PTR",
+			hwValue: "www.example.com.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, err := hwRR(tt.record)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rr.Name != tt.hwName || rr.Type != tt.hwType || rr.Data != tt.hwValue {
+				t.Errorf("expected %s %s %q, got %s %s %q", tt.hwName, tt.hwType, tt.hwValue, rr.Name, rr.Type, rr.Data)
+			}
+
+			parsed, err := parseRecord(rr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := tt.parsed
+			if want == nil {
+				want = tt.record
+			}
+			if !reflect.DeepEqual(parsed, want) {
+				t.Errorf("expected %#v, got %#v", want, parsed)
+			}
+		})
+	}
+}
+
+func TestParseRecordInvalid(t *testing.T) {
+	for _, rr := range []libdns.RR{
+		{Name: "www", Type: "A", Data: "not an address"},
+		{Name: "@", Type: "MX", Data: "mail.example.com."},
+		{Name: "@", Type: "MX", Data: "70000 mail.example.com."},
+		{Name: "_sip._tcp", Type: "SRV", Data: "10 5 sip.example.com."},
+		{Name: "@", Type: "CAA", Data: "0 issue"},
+	} {
+		if _, err := parseRecord(rr); err == nil {
+			t.Errorf("expected an error for %s %q", rr.Type, rr.Data)
+		}
+	}
+}
+
+func TestProviderUnsupportedType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	p := &Provider{AccessKeyId: "ak", SecretAccessKey: "sk", Endpoint: server.URL}
+	records := []libdns.Record{
+		libdns.Address{Name: "www", IP: netip.MustParseAddr("192.0.2.1")},
+		libdns.RR{Name: "www", Type: "SSHFP", Data: "1 1 0123456789abcdef"},
+	}
+	calls := map[string]func(context.Context, string, []libdns.Record) ([]libdns.Record, error){
+		"append":  p.AppendRecords,
+		"set":     p.SetRecords,
+		"delete":  p.DeleteRecords,
+		"disable": p.DisableRecords,
+	}
+	for name, call := range calls {
+		_, err := call(context.Background(), "example.com.", records)
+		var typeErr *UnsupportedTypeError
+		if !errors.As(err, &typeErr) || typeErr.Type != "SSHFP" {
+			t.Errorf("%s: expected an unsupported type error, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package huaweicloud
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed API requests are retried. Every attempt
+// is signed again, since signatures embed the request time.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// BaseDelay is the delay before the first retry. It doubles with every
+	// further attempt.
+	BaseDelay time.Duration `json:"base_delay,omitempty"`
+	// MaxDelay caps the exponential backoff. A longer Retry-After sent by
+	// the server is still honored, up to MaxRetryAfter.
+	MaxDelay time.Duration `json:"max_delay,omitempty"`
+	// Jitter is the fraction of each delay, between 0 and 1, that is
+	// randomized to spread out retries of concurrent clients. Nil disables
+	// jitter, except in Provider.Retry where it keeps the default.
+	Jitter *float64 `json:"jitter,omitempty"`
+	// RetryStatuses lists the HTTP status codes that are retried.
+	RetryStatuses []int `json:"retry_statuses,omitempty"`
+	// RetryNonIdempotent allows POST requests to be retried after failures
+	// that may have reached the server. By default they are only retried
+	// when the request was throttled or the connection could not be made,
+	// so that records are never created twice.
+	RetryNonIdempotent bool `json:"retry_non_idempotent,omitempty"`
+}
+
+// MaxRetryAfter bounds how long a request waits for the delay requested by
+// the Retry-After header of a response.
+const MaxRetryAfter = time.Minute
+
+// DefaultRetryPolicy returns the policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	jitter := 0.2
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		Jitter:      &jitter,
+		RetryStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// withDefaults returns the policy with its zero fields set to their value
+// in DefaultRetryPolicy, so that a partial policy from configuration only
+// overrides what it sets.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+	if p.BaseDelay == 0 {
+		p.BaseDelay = defaults.BaseDelay
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = defaults.MaxDelay
+	}
+	if p.Jitter == nil {
+		p.Jitter = defaults.Jitter
+	}
+	if p.RetryStatuses == nil {
+		p.RetryStatuses = defaults.RetryStatuses
+	}
+	return p
+}
+
+// WithRetryPolicy sets the retry policy of the client.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// shouldRetry reports whether a request with the given method that failed
+// with err may be attempted again.
+func (p RetryPolicy) shouldRetry(method string, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if !p.retryStatus(apiErr.StatusCode) {
+			return false
+		}
+		return isIdempotent(method) || p.RetryNonIdempotent || IsThrottled(err)
+	}
+
+	// Only network errors are retried. Failures to authenticate, sign the
+	// request or decode a successful response would fail again.
+	var tErr *transportError
+	if !errors.As(err, &tErr) {
+		return false
+	}
+	// The request may or may not have reached the server, unless the
+	// connection was never established.
+	return isIdempotent(method) || p.RetryNonIdempotent || isDialError(err)
+}
+
+// transportError is a network error sending a request or reading its
+// response, as opposed to an error response or a local failure.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+func (p RetryPolicy) retryStatus(code int) bool {
+	for _, status := range p.RetryStatuses {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry, starting at 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter != nil && *p.Jitter > 0 {
+		delay -= time.Duration(*p.Jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
+
+// retryAfter returns the delay requested by a Retry-After header, which is
+// either a number of seconds or an HTTP date.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// sleep waits for the delay, or returns false if the context ends first or
+// its deadline leaves no room for another attempt.
+func sleep(ctx context.Context, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return false
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// unwrapTransportError returns the underlying error of a transport error,
+// which only marks errors as retryable.
+func unwrapTransportError(err error) error {
+	if tErr, ok := err.(*transportError); ok {
+		return tErr.err
+	}
+	return err
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,90 @@
+package huaweicloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	throttled := &APIError{StatusCode: http.StatusTooManyRequests}
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+	badRequest := &APIError{StatusCode: http.StatusBadRequest}
+	reset := &transportError{err: errors.New("connection reset by peer")}
+	refused := &transportError{err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	authFailed := errors.New("no credentials found")
+	decode := &json.SyntaxError{Offset: 1}
+
+	tests := []struct {
+		method string
+		err    error
+		retry  bool
+	}{
+		{http.MethodGet, throttled, true},
+		{http.MethodGet, unavailable, true},
+		{http.MethodGet, badRequest, false},
+		{http.MethodGet, reset, true},
+		{http.MethodGet, context.DeadlineExceeded, false},
+		{http.MethodGet, authFailed, false},
+		{http.MethodGet, decode, false},
+		{http.MethodPut, unavailable, true},
+		{http.MethodPost, throttled, true},
+		{http.MethodPost, unavailable, false},
+		{http.MethodPost, reset, false},
+		{http.MethodPost, refused, true},
+	}
+	for _, tt := range tests {
+		if got := policy.shouldRetry(tt.method, tt.err); got != tt.retry {
+			t.Errorf("%s %v: expected retry %v, got %v", tt.method, tt.err, tt.retry, got)
+		}
+	}
+
+	policy.RetryNonIdempotent = true
+	if !policy.shouldRetry(http.MethodPost, unavailable) {
+		t.Errorf("expected POST to be retried when non-idempotent retries are allowed")
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range expected {
+		if got := policy.backoff(i + 1); got != delay {
+			t.Errorf("retry %d: expected %v, got %v", i+1, delay, got)
+		}
+	}
+
+	jitter := 0.5
+	policy.Jitter = &jitter
+	for i := 0; i < 100; i++ {
+		if got := policy.backoff(2); got < time.Second || got > 2*time.Second {
+			t.Fatalf("jittered delay %v out of range", got)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	header := http.Header{}
+
+	header.Set("Retry-After", "3")
+	if got := retryAfter(header, now); got != 3*time.Second {
+		t.Errorf("expected 3s, got %v", got)
+	}
+
+	header.Set("Retry-After", now.Add(time.Minute).Format(http.TimeFormat))
+	if got := retryAfter(header, now); got != time.Minute {
+		t.Errorf("expected 1m, got %v", got)
+	}
+
+	header.Set("Retry-After", "soon")
+	if got := retryAfter(header, now); got != 0 {
+		t.Errorf("expected 0, got %v", got)
+	}
+}
@@ -0,0 +1,157 @@
+package huaweicloud
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// rrsetKey identifies an RRset. Huawei Cloud stores each RRset as a single
+// recordset holding all of its values, one per resolution line.
+type rrsetKey struct {
+	Name string
+	Type string
+	Line string
+}
+
+func (r RecordSet) key() rrsetKey {
+	return rrsetKey{
+		Name: strings.ToLower(strings.TrimSuffix(r.Name, ".")),
+		Type: strings.ToUpper(r.Type),
+		Line: lineOrDefault(r.Line),
+	}
+}
+
+// groupRRsets converts libdns records into recordsets, one per (name, type,
+// line), in order of first appearance. The TTL and weight of the first
+// record of each RRset win, since an RRset has a single TTL and weight.
+func groupRRsets(zone string, records []libdns.Record) ([]RecordSet, error) {
+	var sets []RecordSet
+	index := make(map[rrsetKey]int)
+	for _, rec := range records {
+		hwRec, err := hwRecord(zone, rec)
+		if err != nil {
+			return nil, fmt.Errorf("parsing libdns record %+v: %w", rec, err)
+		}
+		i, ok := index[hwRec.key()]
+		if !ok {
+			i = len(sets)
+			index[hwRec.key()] = i
+			sets = append(sets, RecordSet{Name: hwRec.Name, Type: hwRec.Type, Ttl: hwRec.Ttl, Line: hwRec.Line, Weight: hwRec.Weight})
+		}
+		sets[i].Records, _ = unionValues(hwRec.Type, sets[i].Records, hwRec.Records)
+	}
+	return sets, nil
+}
+
+// unionValues appends the values not yet present in dst, and returns the
+// result along with the values that were actually added. Values are those
+// of a recordset of the given type, compared by valueKey.
+func unionValues(typ string, dst, values []string) ([]string, []string) {
+	seen := make(map[string]bool, len(dst))
+	for _, v := range dst {
+		seen[valueKey(typ, v)] = true
+	}
+	var added []string
+	for _, v := range values {
+		key := valueKey(typ, v)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		dst = append(dst, v)
+		added = append(added, v)
+	}
+	return dst, added
+}
+
+// matchValue returns the value of rr as compared with the values of the
+// recordset rs, see valueKey. A record without a type is matched against
+// recordsets of every type, and simply does not match those its value
+// cannot be read as; an error is only returned for a value that cannot be
+// read as the type the record asks for.
+func matchValue(zone string, rr libdns.RR, rs RecordSet) (string, bool, error) {
+	if rr.Type != "" && !strings.EqualFold(rr.Type, rs.Type) {
+		return "", false, nil
+	}
+	hwRec, err := hwRecord(zone, libdns.RR{Name: rr.Name, Type: rs.Type, Data: rr.Data})
+	if err != nil {
+		if rr.Type == "" {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("parsing libdns record %+v: %w", rr, err)
+	}
+	return valueKey(rs.Type, hwRec.Records[0]), true, nil
+}
+
+// valueKey returns the value of a recordset of the given type in a form
+// that only differs between values that mean different records. Huawei
+// Cloud keeps values as they were written, so the same TXT text may be
+// split into different character-strings, a CAA value may be unquoted, and
+// hostnames may differ in case. Values that cannot be parsed are compared
+// as they are.
+func valueKey(typ, value string) string {
+	rec, err := parseRecord(libdns.RR{Name: "@", Type: strings.ToUpper(typ), Data: value})
+	if err != nil {
+		return value
+	}
+	switch rec := rec.(type) {
+	case libdns.CNAME:
+		rec.Target = strings.ToLower(absoluteTarget(rec.Target))
+		return rec.RR().Data
+	case libdns.NS:
+		rec.Target = strings.ToLower(absoluteTarget(rec.Target))
+		return rec.RR().Data
+	case libdns.MX:
+		rec.Target = strings.ToLower(absoluteTarget(rec.Target))
+		return rec.RR().Data
+	case libdns.SRV:
+		rec.Target = strings.ToLower(absoluteTarget(rec.Target))
+		return rec.RR().Data
+	case libdns.ServiceBinding:
+		rec.Target = strings.ToLower(absoluteTarget(rec.Target))
+		return rec.RR().Data
+	case libdns.RR:
+		return rec.Data
+	default:
+		return rec.RR().Data
+	}
+}
+
+// containsValue reports whether values, those of a recordset of the given
+// type, hold a value with the given key.
+func containsValue(typ string, values []string, key string) bool {
+	for _, v := range values {
+		if valueKey(typ, v) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// sameWeight reports whether updating a recordset of weight current to
+// weight wanted is a no-op. A nil wanted weight keeps the current one.
+func sameWeight(current, wanted *int32) bool {
+	return wanted == nil || (current != nil && *current == *wanted)
+}
+
+// sameValues reports whether a and b, the values of recordsets of the given
+// type, hold the same set of values regardless of order and layout.
+func sameValues(typ string, a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]int, len(a))
+	for _, v := range a {
+		seen[valueKey(typ, v)]++
+	}
+	for _, v := range b {
+		key := valueKey(typ, v)
+		if seen[key] == 0 {
+			return false
+		}
+		seen[key]--
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package huaweicloud
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestGroupRRsets(t *testing.T) {
+	rrsets, err := groupRRsets("example.com.", []libdns.Record{
+		libdns.Address{Name: "www", TTL: 5 * time.Minute, IP: netip.MustParseAddr("192.0.2.1")},
+		libdns.TXT{Name: "www", TTL: time.Minute, Text: "hello"},
+		libdns.Address{Name: "WWW.example.com.", TTL: time.Hour, IP: netip.MustParseAddr("192.0.2.2")},
+		libdns.Address{Name: "www", TTL: 5 * time.Minute, IP: netip.MustParseAddr("192.0.2.1")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(rrsets) != 2 {
+		t.Fatalf("expected 2 RRsets, got %d: %+v", len(rrsets), rrsets)
+	}
+	a := rrsets[0]
+	if a.Name != "www.example.com." || a.Type != "A" || a.Ttl != 300 {
+		t.Errorf("unexpected A RRset %+v", a)
+	}
+	if !sameValues("A", a.Records, []string{"192.0.2.2", "192.0.2.1"}) {
+		t.Errorf("unexpected A values %v", a.Records)
+	}
+	if txt := rrsets[1]; txt.Type != "TXT" || len(txt.Records) != 1 {
+		t.Errorf("unexpected TXT RRset %+v", txt)
+	}
+}
+
+func TestGroupRRsetsByLine(t *testing.T) {
+	rrsets, err := groupRRsets("example.com.", []libdns.Record{
+		libdns.Address{Name: "www", IP: netip.MustParseAddr("192.0.2.1")},
+		libdns.Address{Name: "www", IP: netip.MustParseAddr("192.0.2.2"), ProviderData: RecordData{Line: "Dianxin"}},
+		libdns.Address{Name: "www", IP: netip.MustParseAddr("192.0.2.3"), ProviderData: RecordData{Line: LineDefault}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(rrsets) != 2 {
+		t.Fatalf("expected 2 RRsets, got %d: %+v", len(rrsets), rrsets)
+	}
+	if !sameValues("A", rrsets[0].Records, []string{"192.0.2.1", "192.0.2.3"}) {
+		t.Errorf("expected the default line values together, got %v", rrsets[0].Records)
+	}
+	if rrsets[1].Line != "Dianxin" || !sameValues("A", rrsets[1].Records, []string{"192.0.2.2"}) {
+		t.Errorf("unexpected Dianxin RRset %+v", rrsets[1])
+	}
+}
@@ -23,6 +23,7 @@ const (
 	HeaderXHost          = "host"
 	HeaderXAuthorization = "Authorization"
 	HeaderXContentSha256 = "X-Sdk-Content-Sha256"
+	HeaderXSecurityToken = "X-Security-Token"
 )
 
 func hmacsha256(keyByte []byte, dataStr string) ([]byte, error) {
@@ -167,6 +168,9 @@ func AuthHeaderValue(signatureStr, accessKeyStr string, signedHeaders []string)
 type Signer struct {
 	Key    string
 	Secret string
+	// SecurityToken is set for temporary credentials and sent as the
+	// X-Security-Token header, which is signed along with the request.
+	SecurityToken string
 }
 
 // Sign SignRequest set Authorization header
@@ -181,6 +185,9 @@ func (s *Signer) Sign(request *http.Request) error {
 		t = time.Now()
 		request.Header.Set(HeaderXDateTime, t.UTC().Format(DateFormat))
 	}
+	if s.SecurityToken != "" {
+		request.Header.Set(HeaderXSecurityToken, s.SecurityToken)
+	}
 	signedHeaders := SignedHeaders(request)
 	canonicalRequest, err := CanonicalRequest(request, signedHeaders)
 	if err != nil {
@@ -0,0 +1,80 @@
+package huaweicloud
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSigner(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		body          string
+		header        map[string]string
+		securityToken string
+		canonical     string
+		authorization string
+	}{
+		{
+			name:          "security token",
+			method:        http.MethodGet,
+			url:           "https://dns.cn-south-1.myhuaweicloud.com/v2/zones?search_mode=equal&name=example.com",
+			securityToken: "token-value",
+			canonical: "GET\n/v2/zones/\nname=example.com&search_mode=equal\n" +
+				"x-sdk-date:20240101T000000Z\nx-security-token:token-value\n\n" +
+				"x-sdk-date;x-security-token\n" +
+				"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			authorization: "SDK-HMAC-SHA256 Access=access-key, SignedHeaders=x-sdk-date;x-security-token, " +
+				"Signature=78b0b604f2134a27ee8ae5310d77e574dea8497ee83b86d8024d4c37fca58314",
+		},
+		{
+			name:   "body",
+			method: http.MethodPost,
+			url:    "https://dns.cn-south-1.myhuaweicloud.com/v2/zones/zone-id/recordsets",
+			body:   `{"name":"www.example.com.","type":"A","ttl":300,"records":["192.0.2.1"]}`,
+			header: map[string]string{"Content-Type": "application/json"},
+			canonical: "POST\n/v2/zones/zone-id/recordsets/\n\n" +
+				"content-type:application/json\nx-sdk-date:20240101T000000Z\n\n" +
+				"content-type;x-sdk-date\n" +
+				"0da0d1e9a3c391a406897048d69c497e6b78872583538ee3f6ae46b7710bc5fd",
+			authorization: "SDK-HMAC-SHA256 Access=access-key, SignedHeaders=content-type;x-sdk-date, " +
+				"Signature=233a8279bdbc4a91a7eaa1429635de7a4d5bacb47a29392e7f280deaa5e1922d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set(HeaderXDateTime, "20240101T000000Z")
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+
+			signer := &Signer{Key: "access-key", Secret: "secret-key", SecurityToken: tt.securityToken}
+			if err := signer.Sign(req); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := req.Header.Get(HeaderXAuthorization); got != tt.authorization {
+				t.Errorf("expected authorization\n%s\ngot\n%s", tt.authorization, got)
+			}
+			if tt.securityToken != "" && req.Header.Get(HeaderXSecurityToken) != tt.securityToken {
+				t.Errorf("expected %s header to be set", HeaderXSecurityToken)
+			}
+
+			req.Header.Del(HeaderXAuthorization)
+			canonical, err := CanonicalRequest(req, SignedHeaders(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if canonical != tt.canonical {
+				t.Errorf("expected canonical request\n%q\ngot\n%q", tt.canonical, canonical)
+			}
+		})
+	}
+}
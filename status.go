@@ -0,0 +1,188 @@
+package huaweicloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// SetRecordStatus enables or disables the recordset with the given ID, with
+// status StatusEnable or StatusDisable. A disabled recordset is not served
+// but keeps its ID, TTL, values and description.
+func (c *Client) SetRecordStatus(ctx context.Context, recordId, status string) (*RecordSet, error) {
+	body, err := json.Marshal(statusRequest{Status: status})
+	if err != nil {
+		return nil, err
+	}
+
+	url := c.getVersionURL("v2.1")
+	url = url.JoinPath("recordsets", recordId, "statuses", "set")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(RecordSet)
+	if err = c.doAPIRequest(req, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DisableRecords disables the recordsets holding the given records, for
+// example during maintenance, and returns their records. Statuses apply to
+// whole recordsets, so every value of a matched recordset is disabled. As
+// with DeleteRecords, empty type, TTL or data fields match any value, and
+// records that do not exist are ignored.
+func (p *Provider) DisableRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return p.setRecordStatus(ctx, zone, records, StatusDisable)
+}
+
+// EnableRecords enables the recordsets holding the given records again and
+// returns their records. See DisableRecords.
+func (p *Provider) EnableRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return p.setRecordStatus(ctx, zone, records, StatusEnable)
+}
+
+func (p *Provider) setRecordStatus(ctx context.Context, zone string, records []libdns.Record, status string) ([]libdns.Record, error) {
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTypes(records); err != nil {
+		return nil, err
+	}
+
+	var matched []RecordSet
+	seen := make(map[string]bool)
+	lookups := make(map[rrsetKey][]RecordSet)
+	for _, record := range records {
+		rr := record.RR()
+		if rr.Name == "" {
+			return nil, fmt.Errorf("setting status of record %+v: name is required", rr)
+		}
+		data, _ := recordData(record)
+
+		key := rrsetKey{Name: strings.ToLower(fqdn(rr.Name, zone)), Type: strings.ToUpper(rr.Type), Line: data.Line}
+		recordSets, ok := lookups[key]
+		if !ok {
+			recordSets, err = client.FindRecordSetsByLine(ctx, zone, rr.Name, rr.Type, data.Line)
+			if err != nil {
+				return nil, err
+			}
+			lookups[key] = recordSets
+		}
+
+		for _, rs := range recordSets {
+			if seen[rs.Id] || (rr.TTL != 0 && int32(rr.TTL.Seconds()) != rs.Ttl) {
+				continue
+			}
+			if rr.Data != "" {
+				value, ok, err := matchValue(zone, rr, rs)
+				if err != nil {
+					return nil, err
+				}
+				if !ok || !containsValue(rs.Type, rs.Records, value) {
+					continue
+				}
+			}
+			seen[rs.Id] = true
+			matched = append(matched, rs)
+		}
+	}
+
+	var results []libdns.Record
+	var settled []change
+	outcomes := p.applyStatus(ctx, client, matched, status)
+	for _, outcome := range outcomes {
+		if outcome.Err != nil {
+			continue
+		}
+		settled = append(settled, change{id: outcome.RecordSet.Id})
+
+		libdnsRecs, err := outcome.RecordSet.libdnsRecord(zone)
+		if err != nil {
+			return nil, fmt.Errorf("parsing Huawei Cloud DNS record %+v: %v", outcome.RecordSet, err)
+		}
+		results = append(results, libdnsRecs...)
+	}
+	if err := batchError(outcomes); err != nil {
+		return results, err
+	}
+
+	if err := p.waitForActive(ctx, client, zone, settled); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// applyStatus sets the status of the recordsets, through the batch endpoint
+// when there are enough of them, and returns the outcome per recordset.
+func (p *Provider) applyStatus(ctx context.Context, client *Client, recordSets []RecordSet, status string) []BatchResult {
+	results := make([]BatchResult, len(recordSets))
+	for i, rs := range recordSets {
+		results[i].RecordSet = rs
+	}
+
+	threshold := p.BatchThreshold
+	if threshold == 0 {
+		threshold = defaultBatchThreshold
+	}
+	batched := make(map[string]RecordSet)
+	failed := make(map[string]error)
+	if threshold > 0 && len(recordSets) >= threshold {
+		for start := 0; start < len(recordSets); start += maxBatchSize {
+			end := start + maxBatchSize
+			if end > len(recordSets) {
+				end = len(recordSets)
+			}
+			ids := make([]string, 0, end-start)
+			for _, rs := range recordSets[start:end] {
+				ids = append(ids, rs.Id)
+			}
+			resp, err := client.BatchSetRecordStatus(ctx, ids, status)
+			if err != nil {
+				// Any failure but an unavailable batch endpoint would recur
+				// for every recordset.
+				if ctx.Err() != nil || !batchUnavailable(err) {
+					for _, id := range ids {
+						failed[id] = err
+					}
+				}
+				continue
+			}
+			for _, rs := range resp {
+				batched[rs.Id] = rs
+			}
+		}
+	}
+
+	for i, rs := range recordSets {
+		if err, ok := failed[rs.Id]; ok {
+			results[i].Err = err
+			continue
+		}
+		if resp, ok := batched[rs.Id]; ok {
+			// The batch response may only hold the ID and status.
+			rs.Status = resp.Status
+			results[i].RecordSet = rs
+			continue
+		}
+		resp, err := client.SetRecordStatus(ctx, rs.Id, status)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		rs.Status = resp.Status
+		results[i].RecordSet = rs
+	}
+	return results
+}
@@ -0,0 +1,96 @@
+package huaweicloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestProviderDisableRecords(t *testing.T) {
+	var mu sync.Mutex
+	recordSets := map[string]*RecordSet{
+		"www-id": {Id: "www-id", Name: "www.example.com.", Type: "A", Ttl: 300, Records: []string{"192.0.2.1", "192.0.2.2"}, Status: StatusActive, Description: "web"},
+		"mx-id":  {Id: "mx-id", Name: "example.com.", Type: "MX", Ttl: 300, Records: []string{"10 mail.example.com."}, Status: StatusActive},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/v2/zones":
+			_ = json.NewEncoder(w).Encode(ListZonesResponse{Zones: []Zone{{Id: "zone-id", Name: "example.com."}}})
+		case r.URL.Path == "/v2/zones/zone-id/recordsets":
+			var resp ListRecordsResponse
+			for _, id := range []string{"www-id", "mx-id"} {
+				rs := recordSets[id]
+				if name := r.URL.Query().Get("name"); name == "" || name == rs.Name {
+					resp.RecordSets = append(resp.RecordSets, *rs)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		case strings.HasPrefix(r.URL.Path, "/v2.1/recordsets/") && strings.HasSuffix(r.URL.Path, "/statuses/set"):
+			var req statusRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			rs := recordSets[strings.Split(r.URL.Path, "/")[3]]
+			rs.Status = StatusActive
+			if req.Status == StatusDisable {
+				rs.Status = StatusDisable
+			}
+			_ = json.NewEncoder(w).Encode(rs)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p := &Provider{AccessKeyId: "ak", SecretAccessKey: "sk", Endpoint: server.URL}
+	ctx := context.Background()
+
+	disabled, err := p.DisableRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"},
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"},
+		libdns.RR{Name: "missing", Type: "A"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(disabled) != 2 {
+		t.Fatalf("expected the 2 records of the www recordset, got %+v", disabled)
+	}
+	if data, ok := recordData(disabled[0]); !ok || !data.Disabled {
+		t.Errorf("expected disabled records to be marked in ProviderData, got %+v", disabled[0])
+	}
+	if rs := recordSets["www-id"]; rs.Status != StatusDisable || rs.Description != "web" || rs.Ttl != 300 {
+		t.Errorf("unexpected recordset after disabling %+v", rs)
+	}
+
+	records, err := p.GetRecords(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 3 {
+		t.Errorf("expected disabled records to be included by default, got %+v", records)
+	}
+	p.ExcludeDisabled = true
+	records, err = p.GetRecords(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].RR().Type != "MX" {
+		t.Errorf("expected only the MX record, got %+v", records)
+	}
+
+	if _, err := p.EnableRecords(ctx, "example.com.", []libdns.Record{libdns.RR{Name: "www"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rs := recordSets["www-id"]; rs.Status != StatusActive {
+		t.Errorf("expected the recordset to be enabled, got %+v", rs)
+	}
+}
@@ -0,0 +1,136 @@
+package huaweicloud
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/libdns/huaweicloud/internal/dnsutil"
+)
+
+// maxStringLength is the maximum length in bytes of a DNS character-string.
+const maxStringLength = 255
+
+// encodeTXT encodes the text of a TXT record as RFC 1035 character-strings,
+// the layout Huawei Cloud expects: quoted, with quotation marks, backslashes
+// and control characters escaped, and split into strings of at most 255
+// bytes. Strings are not split within a UTF-8 character.
+func encodeTXT(text string) string {
+	if text == "" {
+		return `""`
+	}
+
+	var sb strings.Builder
+	for len(text) > 0 {
+		n := len(text)
+		if n > maxStringLength {
+			n = maxStringLength
+			for n > maxStringLength-utf8.UTFMax && !utf8.RuneStart(text[n]) {
+				n--
+			}
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteByte('"')
+		writeEscaped(&sb, text[:n])
+		sb.WriteByte('"')
+		text = text[n:]
+	}
+	return sb.String()
+}
+
+// writeEscaped writes s with the escapes of a quoted character-string.
+// Valid UTF-8 is kept as is; other bytes above ASCII are escaped.
+func writeEscaped(sb *strings.Builder, s string) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c < ' ' || c == 0x7f:
+			fmt.Fprintf(sb, "\\%03d", c)
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				fmt.Fprintf(sb, "\\%03d", c)
+			} else {
+				sb.WriteString(s[i : i+size])
+			}
+			i += size
+			continue
+		default:
+			sb.WriteByte(c)
+		}
+		i++
+	}
+}
+
+// errUnterminatedString is returned for a quoted character-string without
+// its closing quotation mark.
+var errUnterminatedString = errors.New("unterminated character-string")
+
+// decodeTXT decodes the RFC 1035 character-strings of a TXT record value,
+// quoted or not, and joins them into the text of the record.
+func decodeTXT(value string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(value); {
+		switch c := value[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			end, err := decodeString(&sb, value, i+1, true)
+			if err != nil {
+				return "", err
+			}
+			i = end + 1
+		default:
+			end, err := decodeString(&sb, value, i, false)
+			if err != nil {
+				return "", err
+			}
+			i = end
+		}
+	}
+	return sb.String(), nil
+}
+
+// decodeString writes the unescaped character-string starting at
+// value[start:] and returns the offset of its end: the closing quotation
+// mark if quoted, or the whitespace or end of value otherwise.
+func decodeString(sb *strings.Builder, value string, start int, quoted bool) (int, error) {
+	for i := start; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quoted && c == '"':
+			return i, nil
+		case !quoted && (c == ' ' || c == '\t'):
+			return i, nil
+		case !quoted && c == '"':
+			return 0, fmt.Errorf("unexpected quotation mark at offset %d", i)
+		case c != '\\':
+			sb.WriteByte(c)
+		case i+1 == len(value):
+			return 0, errors.New("trailing backslash")
+		case dnsutil.IsDigit(value[i+1]):
+			if i+3 >= len(value) || !dnsutil.IsDigit(value[i+2]) || !dnsutil.IsDigit(value[i+3]) {
+				return 0, fmt.Errorf("invalid escape at offset %d", i)
+			}
+			n := int(value[i+1]-'0')*100 + int(value[i+2]-'0')*10 + int(value[i+3]-'0')
+			if n > 255 {
+				return 0, fmt.Errorf("invalid escape %s", value[i:i+4])
+			}
+			sb.WriteByte(byte(n))
+			i += 3
+		default:
+			sb.WriteByte(value[i+1])
+			i++
+		}
+	}
+	if quoted {
+		return 0, errUnterminatedString
+	}
+	return len(value), nil
+}
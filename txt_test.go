@@ -0,0 +1,98 @@
+package huaweicloud
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodeTXT(t *testing.T) {
+	long := strings.Repeat("a", 600)
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "simple", text: "hello", want: `"hello"`},
+		{name: "empty", text: "", want: `""`},
+		{name: "spaces", text: "v=spf1 -all", want: `"v=spf1 -all"`},
+		{name: "quotes", text: `say "hi"`, want: `"say \"hi\""`},
+		{name: "backslashes", text: `C:\dir\`, want: `"C:\\dir\\"`},
+		{name: "control characters", text: "a\tb\nc\x7f", want: `"a\009b\010c\127"`},
+		{name: "utf-8", text: "héllo 世界", want: `"héllo 世界"`},
+		{name: "invalid utf-8", text: "a\xffb", want: `"a\255b"`},
+		{name: "exactly 255 bytes", text: long[:255], want: `"` + long[:255] + `"`},
+		{name: "256 bytes", text: long[:256], want: `"` + long[:255] + `" "a"`},
+		{name: "600 bytes", text: long, want: `"` + long[:255] + `" "` + long[:255] + `" "` + long[:90] + `"`},
+		{
+			// The 3-byte character straddling byte 255 moves to the next string.
+			name: "utf-8 at boundary",
+			text: long[:254] + "世" + "b",
+			want: `"` + long[:254] + `" "世b"`,
+		},
+		{
+			// Escapes do not count towards the length of a string.
+			name: "escapes at boundary",
+			text: strings.Repeat(`"`, 256),
+			want: `"` + strings.Repeat(`\"`, 255) + `" "\""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeTXT(tt.text)
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			decoded, err := decodeTXT(got)
+			if err != nil {
+				t.Fatalf("unexpected error decoding %q: %v", got, err)
+			}
+			if decoded != tt.text {
+				t.Errorf("expected %q to decode to %q, got %q", got, tt.text, decoded)
+			}
+		})
+	}
+}
+
+func TestDecodeTXT(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "quoted", value: `"hello"`, want: "hello"},
+		{name: "unquoted", value: `hello`, want: "hello"},
+		{name: "empty string", value: `""`, want: ""},
+		{name: "empty value", value: ``, want: ""},
+		{name: "joined strings", value: `"v=DKIM1; k=rsa; " "p=MIGf"`, want: "v=DKIM1; k=rsa; p=MIGf"},
+		{name: "unquoted strings", value: "abc def\tghi", want: "abcdefghi"},
+		{name: "mixed strings", value: `abc "d e"`, want: "abcd e"},
+		{name: "escaped quotes", value: `"say \"hi\""`, want: `say "hi"`},
+		{name: "escaped characters", value: `"a\;b\\c"`, want: `a;b\c`},
+		{name: "decimal escapes", value: `"a\009b\010\255"`, want: "a\tb\n\xff"},
+		{name: "unterminated", value: `"hello`, wantErr: true},
+		{name: "trailing backslash", value: `"hello\`, wantErr: true},
+		{name: "short decimal escape", value: `"a\25"`, wantErr: true},
+		{name: "decimal escape out of range", value: `"\256"`, wantErr: true},
+		{name: "quote inside unquoted", value: `ab"c"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeTXT(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
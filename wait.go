@@ -0,0 +1,127 @@
+package huaweicloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Statuses of zones and recordsets. Changes are applied asynchronously:
+// resources stay in a PENDING_* status until they become ACTIVE or ERROR.
+const (
+	StatusActive        = "ACTIVE"
+	StatusError         = "ERROR"
+	StatusPendingCreate = "PENDING_CREATE"
+	StatusPendingUpdate = "PENDING_UPDATE"
+	StatusPendingDelete = "PENDING_DELETE"
+	StatusDisable       = "DISABLE"
+	StatusFreeze        = "FREEZE"
+	// StatusEnable enables disabled recordsets, see SetRecordStatus.
+	StatusEnable = "ENABLE"
+)
+
+const (
+	// defaultWaitInterval is how often recordsets are polled while waiting.
+	defaultWaitInterval = 2 * time.Second
+	// defaultWaitTimeout bounds how long a call waits for its changes.
+	defaultWaitTimeout = 2 * time.Minute
+)
+
+// ResourceStatusError is returned when a recordset or zone ends up in the
+// ERROR status after a change.
+type ResourceStatusError struct {
+	// Resource is the kind of the failed resource, "zone" or "recordset".
+	Resource string
+	Id       string
+	Name     string
+	Status   string
+}
+
+func (e *ResourceStatusError) Error() string {
+	return fmt.Sprintf("%s %s (%s) is in status %s", e.Resource, e.Name, e.Id, e.Status)
+}
+
+// isPending reports whether a status is transitional.
+func isPending(status string) bool {
+	return strings.HasPrefix(status, "PENDING")
+}
+
+// GetRecordSet returns the recordset with the given ID.
+func (c *Client) GetRecordSet(ctx context.Context, zone, recordId string) (*RecordSet, error) {
+	resp := new(RecordSet)
+	err := c.withZoneId(ctx, zone, func(zoneId string) error {
+		url := c.getRecordsURL()
+		url = url.JoinPath("zones", zoneId, "recordsets", recordId)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+		if err != nil {
+			return err
+		}
+
+		return c.doAPIRequest(req, resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// WaitForRecordSet polls the recordset until its status is no longer
+// pending, or until it is gone if deleted is true. A recordset in the ERROR
+// status is reported as a *ResourceStatusError.
+func (c *Client) WaitForRecordSet(ctx context.Context, zone, recordId string, deleted bool, interval time.Duration) (*RecordSet, error) {
+	if interval <= 0 {
+		interval = defaultWaitInterval
+	}
+
+	for {
+		rs, err := c.GetRecordSet(ctx, zone, recordId)
+		switch {
+		case deleted && isStatus(err, http.StatusNotFound):
+			return nil, nil
+		case err != nil:
+			return nil, err
+		case rs.Status == StatusError:
+			return rs, &ResourceStatusError{Resource: "recordset", Id: rs.Id, Name: rs.Name, Status: rs.Status}
+		case !deleted && !isPending(rs.Status):
+			return rs, nil
+		}
+
+		if !sleep(ctx, interval) {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("waiting for recordset %s: %w", recordId, err)
+			}
+			return nil, fmt.Errorf("waiting for recordset %s: %w", recordId, context.DeadlineExceeded)
+		}
+	}
+}
+
+// change is a recordset modified by a provider call.
+type change struct {
+	id      string
+	deleted bool
+}
+
+// waitForActive blocks until the changed recordsets have settled, if the
+// provider is configured to wait.
+func (p *Provider) waitForActive(ctx context.Context, client *Client, zone string, changes []change) error {
+	if !p.WaitForActive || len(changes) == 0 {
+		return nil
+	}
+
+	timeout := p.WaitTimeout
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, c := range changes {
+		if _, err := client.WaitForRecordSet(ctx, zone, c.id, c.deleted, p.WaitInterval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
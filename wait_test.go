@@ -0,0 +1,66 @@
+package huaweicloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForRecordSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		deleted  bool
+		err      bool
+	}{
+		{name: "created", statuses: []string{StatusPendingCreate, StatusPendingCreate, StatusActive}},
+		{name: "failed", statuses: []string{StatusPendingUpdate, StatusError}, err: true},
+		{name: "deleted", statuses: []string{StatusPendingDelete, ""}, deleted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var polls int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/v2/zones" {
+					_ = json.NewEncoder(w).Encode(ListZonesResponse{Zones: []Zone{{Id: "zone-id", Name: "example.com."}}})
+					return
+				}
+				if !strings.HasSuffix(r.URL.Path, "/recordsets/record-id") {
+					http.NotFound(w, r)
+					return
+				}
+				status := tt.statuses[polls]
+				if polls < len(tt.statuses)-1 {
+					polls++
+				}
+				if status == "" {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"code":"DNS.0305","message":"Record set does not exist."}`))
+					return
+				}
+				_ = json.NewEncoder(w).Encode(RecordSet{Id: "record-id", Name: "www.example.com.", Status: status})
+			}))
+			defer server.Close()
+
+			client := NewClient("ak", "sk", "", WithEndpoint(server.URL))
+			_, err := client.WaitForRecordSet(context.Background(), "example.com.", "record-id", tt.deleted, time.Millisecond)
+
+			var statusErr *ResourceStatusError
+			if tt.err != errors.As(err, &statusErr) {
+				t.Fatalf("expected status error %v, got %v", tt.err, err)
+			}
+			if !tt.err && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if polls != len(tt.statuses)-1 {
+				t.Errorf("expected %d polls until settled, got %d", len(tt.statuses), polls+1)
+			}
+		})
+	}
+}
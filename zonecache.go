@@ -0,0 +1,91 @@
+package huaweicloud
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultZoneCacheTTL is how long a resolved zone ID is reused.
+	defaultZoneCacheTTL = 5 * time.Minute
+	// defaultZoneNegativeCacheTTL is how long a "zone not found" result is reused.
+	defaultZoneNegativeCacheTTL = 30 * time.Second
+)
+
+// zoneCache is a concurrency-safe zone name to zone ID cache. Lookups that
+// found no zone are cached as well, for a shorter period.
+type zoneCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	negativeTTL time.Duration
+	entries     map[string]zoneCacheEntry
+	now         func() time.Time
+}
+
+type zoneCacheEntry struct {
+	id      string
+	err     error
+	expires time.Time
+}
+
+func newZoneCache() *zoneCache {
+	return &zoneCache{
+		ttl:         defaultZoneCacheTTL,
+		negativeTTL: defaultZoneNegativeCacheTTL,
+		entries:     make(map[string]zoneCacheEntry),
+		now:         time.Now,
+	}
+}
+
+// zoneCacheKey normalizes a zone name so that "Example.com." and
+// "example.com" share an entry.
+func zoneCacheKey(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(zone, "."))
+}
+
+// get returns the cached zone ID or lookup error for the zone, and whether
+// a live entry was found.
+func (zc *zoneCache) get(zone string) (string, error, bool) {
+	zc.mu.Lock()
+	defer zc.mu.Unlock()
+
+	key := zoneCacheKey(zone)
+	entry, ok := zc.entries[key]
+	if !ok {
+		return "", nil, false
+	}
+	if !zc.now().Before(entry.expires) {
+		delete(zc.entries, key)
+		return "", nil, false
+	}
+	return entry.id, entry.err, true
+}
+
+// put stores a zone ID, or a "not found" error when id is empty.
+func (zc *zoneCache) put(zone, id string, err error) {
+	ttl := zc.ttl
+	if err != nil {
+		ttl = zc.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	zc.mu.Lock()
+	defer zc.mu.Unlock()
+
+	zc.entries[zoneCacheKey(zone)] = zoneCacheEntry{
+		id:      id,
+		err:     err,
+		expires: zc.now().Add(ttl),
+	}
+}
+
+// invalidate drops the entry for the zone, if any.
+func (zc *zoneCache) invalidate(zone string) {
+	zc.mu.Lock()
+	defer zc.mu.Unlock()
+
+	delete(zc.entries, zoneCacheKey(zone))
+}
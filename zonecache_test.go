@@ -0,0 +1,91 @@
+package huaweicloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZoneCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	zc := newZoneCache()
+	zc.now = func() time.Time { return now }
+
+	zc.put("Example.com.", "zone-id", nil)
+	notFound := &NotFoundError{Resource: "zone", Name: "missing.com"}
+	zc.put("missing.com", "", notFound)
+
+	if id, err, ok := zc.get("example.com"); !ok || err != nil || id != "zone-id" {
+		t.Fatalf("expected cached zone ID, got %q, %v, %v", id, err, ok)
+	}
+	if _, err, ok := zc.get("missing.com."); !ok || err != notFound {
+		t.Fatalf("expected cached not found error, got %v, %v", err, ok)
+	}
+
+	now = now.Add(defaultZoneNegativeCacheTTL)
+	if _, _, ok := zc.get("missing.com."); ok {
+		t.Errorf("expected negative entry to expire")
+	}
+	if _, _, ok := zc.get("example.com."); !ok {
+		t.Errorf("expected positive entry to outlive negative entry")
+	}
+
+	zc.invalidate("EXAMPLE.COM")
+	if _, _, ok := zc.get("example.com."); ok {
+		t.Errorf("expected entry to be invalidated")
+	}
+
+	zc.ttl = -1
+	zc.put("example.com.", "zone-id", nil)
+	if _, _, ok := zc.get("example.com."); ok {
+		t.Errorf("expected caching to be disabled")
+	}
+}
+
+func TestClientStaleZoneId(t *testing.T) {
+	var lookups int
+	zoneId := "old-id"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/zones":
+			lookups++
+			_ = json.NewEncoder(w).Encode(ListZonesResponse{Zones: []Zone{{Id: zoneId, Name: "example.com."}}})
+		case "/v2/zones/new-id/recordsets/rs-id":
+			_ = json.NewEncoder(w).Encode(RecordSet{Id: "rs-id"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			if strings.HasPrefix(r.URL.Path, "/v2/zones/old-id/") {
+				_, _ = w.Write([]byte(`{"code":"DNS.0101","message":"The zone does not exist."}`))
+			} else {
+				_, _ = w.Write([]byte(`{"code":"DNS.0305","message":"The record set does not exist."}`))
+			}
+		}
+	}))
+	defer server.Close()
+	client := NewClient("ak", "sk", "", WithEndpoint(server.URL))
+	ctx := context.Background()
+
+	// A missing recordset does not evict the cached zone ID.
+	zoneId = "new-id"
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetRecordSet(ctx, "example.com.", "missing"); !IsNotFound(err) {
+			t.Fatalf("expected the recordset to be missing, got %v", err)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected 1 zone lookup, got %d", lookups)
+	}
+
+	// A missing zone does, and the request is retried with the new ID.
+	client.zoneCache.put("example.com.", "old-id", nil)
+	if _, err := client.GetRecordSet(ctx, "example.com.", "rs-id"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lookups != 2 {
+		t.Errorf("expected the zone to be looked up again, got %d lookups", lookups)
+	}
+}
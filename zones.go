@@ -0,0 +1,329 @@
+package huaweicloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+const (
+	// ZoneTypePublic selects Internet-facing zones. It is the default.
+	ZoneTypePublic = "public"
+	// ZoneTypePrivate selects zones served only inside associated VPCs.
+	ZoneTypePrivate = "private"
+	// ZoneTypeAuto looks up both public and private zones, and fails if a
+	// zone name matches more than one of them.
+	ZoneTypeAuto = "auto"
+)
+
+// WithZoneType sets which kind of zone the client manages, one of
+// ZoneTypePublic, ZoneTypePrivate or ZoneTypeAuto.
+func WithZoneType(zoneType string) ClientOption {
+	return func(c *Client) {
+		c.zoneType = zoneType
+	}
+}
+
+// WithVpcId restricts private zone lookups to zones associated with the
+// given VPC (router), which disambiguates private zones sharing a name.
+func WithVpcId(vpcId string) ClientOption {
+	return func(c *Client) {
+		c.vpcId = vpcId
+	}
+}
+
+// ListZones returns every zone of the configured type, following
+// pagination until all pages have been fetched.
+func (c *Client) ListZones(ctx context.Context) ([]Zone, error) {
+	return c.IterZones(ctx).All()
+}
+
+// IterZones returns an iterator over the zones of the configured type. With
+// ZoneTypeAuto, public zones are returned first, then private zones.
+func (c *Client) IterZones(ctx context.Context) *Iterator[Zone] {
+	types, err := zoneTypes(c.zoneType)
+	if err != nil {
+		return &Iterator[Zone]{err: err}
+	}
+
+	var it *Iterator[Zone]
+	for i := len(types) - 1; i >= 0; i-- {
+		query := neturl.Values{}
+		query.Set("type", types[i])
+		next := c.iterZones(ctx, query)
+		next.then = it
+		it = next
+	}
+	return it
+}
+
+// GetZone returns the details of a zone, including its SOA settings,
+// serial and number of recordsets.
+func (c *Client) GetZone(ctx context.Context, zone string) (*Zone, error) {
+	return c.zoneRequest(ctx, zone, http.MethodGet, nil)
+}
+
+// CreateZone creates a public or private zone. Private zones must be
+// associated with a VPC (router) on creation. The zone starts in status
+// StatusPendingCreate.
+func (c *Client) CreateZone(ctx context.Context, zone CreateZoneRequest) (*Zone, error) {
+	body, err := json.Marshal(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	url := c.getBaseURL()
+	url = url.JoinPath("zones")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(Zone)
+	if err = c.doAPIRequest(req, resp); err != nil {
+		return nil, err
+	}
+
+	// The zone may have been cached as missing.
+	c.zoneCache.invalidate(zone.Name)
+	return resp, nil
+}
+
+// UpdateZone updates the email, TTL and description of a zone. Empty fields
+// are left unchanged.
+func (c *Client) UpdateZone(ctx context.Context, zone string, update UpdateZoneRequest) (*Zone, error) {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.zoneRequest(ctx, zone, http.MethodPatch, body)
+}
+
+// DeleteZone deletes a zone along with all of its recordsets.
+func (c *Client) DeleteZone(ctx context.Context, zone string) (*Zone, error) {
+	resp, err := c.zoneRequest(ctx, zone, http.MethodDelete, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.zoneCache.invalidate(zone)
+	return resp, nil
+}
+
+// ListZoneRouters returns the VPCs (routers) associated with a private zone.
+func (c *Client) ListZoneRouters(ctx context.Context, zone string) ([]Router, error) {
+	resp, err := c.GetZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Routers, nil
+}
+
+// zoneRequest sends a request for the zone resource itself.
+func (c *Client) zoneRequest(ctx context.Context, zone, method string, body []byte) (*Zone, error) {
+	resp := new(Zone)
+	err := c.withZoneId(ctx, zone, func(zoneId string) error {
+		url := c.getBaseURL()
+		url = url.JoinPath("zones", zoneId)
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url.String(), reqBody)
+		if err != nil {
+			return err
+		}
+
+		return c.doAPIRequest(req, resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListNameservers returns the nameservers serving a zone: hostnames for
+// public zones, addresses for private zones.
+func (c *Client) ListNameservers(ctx context.Context, zone string) ([]Nameserver, error) {
+	resp := new(ListNameserversResponse)
+	err := c.withZoneId(ctx, zone, func(zoneId string) error {
+		url := c.getBaseURL()
+		url = url.JoinPath("zones", zoneId, "nameservers")
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+		if err != nil {
+			return err
+		}
+
+		return c.doAPIRequest(req, resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Nameservers, nil
+}
+
+// AssociateRouter associates a VPC (router) with a private zone.
+func (c *Client) AssociateRouter(ctx context.Context, zone string, router Router) (*Router, error) {
+	return c.routerAction(ctx, zone, "associaterouter", router)
+}
+
+// DisassociateRouter removes the association between a VPC (router) and a
+// private zone.
+func (c *Client) DisassociateRouter(ctx context.Context, zone string, router Router) (*Router, error) {
+	return c.routerAction(ctx, zone, "disassociaterouter", router)
+}
+
+func (c *Client) routerAction(ctx context.Context, zone, action string, router Router) (*Router, error) {
+	body, err := json.Marshal(routerRequest{Router: router})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(Router)
+	err = c.withZoneId(ctx, zone, func(zoneId string) error {
+		url := c.getBaseURL()
+		url = url.JoinPath("zones", zoneId, action)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url.String(), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		return c.doAPIRequest(req, resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c *Client) iterZones(ctx context.Context, query neturl.Values) *Iterator[Zone] {
+	return newIterator(ctx, query, func(ctx context.Context, query neturl.Values) ([]Zone, *Links, *Metadata, error) {
+		url := c.getBaseURL()
+		url = url.JoinPath("zones")
+		url.RawQuery = query.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		resp := new(ListZonesResponse)
+		if err = c.doAPIRequest(req, resp); err != nil {
+			return nil, nil, nil, err
+		}
+
+		for i := range resp.Zones {
+			if resp.Zones[i].ZoneType == "" {
+				resp.Zones[i].ZoneType = query.Get("type")
+			}
+		}
+
+		return resp.Zones, resp.Links, resp.Metadata, nil
+	})
+}
+
+// zoneTypes returns the zone types to query for the given ZoneType.
+func zoneTypes(zoneType string) ([]string, error) {
+	switch zoneType {
+	case "", ZoneTypePublic:
+		return []string{ZoneTypePublic}, nil
+	case ZoneTypePrivate:
+		return []string{ZoneTypePrivate}, nil
+	case ZoneTypeAuto:
+		return []string{ZoneTypePublic, ZoneTypePrivate}, nil
+	default:
+		return nil, fmt.Errorf("unsupported zone type %q, expected %q, %q or %q", zoneType, ZoneTypePublic, ZoneTypePrivate, ZoneTypeAuto)
+	}
+}
+
+// InvalidateZone drops the cached zone ID for the zone, forcing the next
+// call to look it up again.
+func (c *Client) InvalidateZone(zone string) {
+	c.zoneCache.invalidate(zone)
+}
+
+// withZoneId resolves the zone ID and calls fn with it. If fn fails because
+// the zone of a cached ID no longer exists, the stale entry is dropped and
+// fn is retried once with a freshly resolved ID.
+func (c *Client) withZoneId(ctx context.Context, zone string, fn func(zoneId string) error) error {
+	zoneId, cached, err := c.resolveZoneId(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	err = fn(zoneId)
+	if !cached || !isZoneGone(err, zoneId) {
+		return err
+	}
+
+	c.zoneCache.invalidate(zone)
+	zoneId, _, err = c.resolveZoneId(ctx, zone)
+	if err != nil {
+		return err
+	}
+	return fn(zoneId)
+}
+
+// resolveZoneId returns the zone ID from the cache or the API, and whether
+// it was served from the cache.
+func (c *Client) resolveZoneId(ctx context.Context, zone string) (string, bool, error) {
+	if zoneId, err, ok := c.zoneCache.get(zone); ok {
+		return zoneId, true, err
+	}
+
+	zoneId, err := c.lookupZoneId(ctx, zone)
+	if err == nil || isZoneNotFound(err) {
+		c.zoneCache.put(zone, zoneId, err)
+	}
+	return zoneId, false, err
+}
+
+func (c *Client) lookupZoneId(ctx context.Context, zone string) (string, error) {
+	zone = strings.TrimSuffix(zone, ".")
+
+	types, err := zoneTypes(c.zoneType)
+	if err != nil {
+		return "", err
+	}
+
+	var zones []Zone
+	for _, zoneType := range types {
+		query := neturl.Values{}
+		query.Set("type", zoneType)
+		query.Set("name", zone)
+		query.Set("search_mode", "equal")
+		found, err := c.iterZones(ctx, query).All()
+		if err != nil {
+			return "", err
+		}
+		for _, z := range found {
+			if z.ZoneType == ZoneTypePrivate && c.vpcId != "" && !z.hasRouter(c.vpcId) {
+				continue
+			}
+			zones = append(zones, z)
+		}
+	}
+
+	if len(zones) == 0 {
+		return "", &NotFoundError{Resource: "zone", Name: zone}
+	}
+	if len(zones) != 1 {
+		var kinds []string
+		for _, z := range zones {
+			kinds = append(kinds, z.ZoneType)
+		}
+		return "", fmt.Errorf("returned more than one zone for %q, expected one, actual %d (%s); set the zone type or VPC ID to disambiguate",
+			zone, len(zones), strings.Join(kinds, ", "))
+	}
+
+	return zones[0].Id, nil
+}
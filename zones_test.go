@@ -0,0 +1,92 @@
+package huaweicloud_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/huaweicloud"
+	"github.com/libdns/huaweicloud/huaweicloudtest"
+)
+
+func TestClientZones(t *testing.T) {
+	server := huaweicloudtest.NewServer(map[string]string{"ak": "sk"})
+	defer server.Close()
+	retry := huaweicloud.DefaultRetryPolicy()
+	retry.BaseDelay = time.Millisecond
+	client := huaweicloud.NewClient("ak", "sk", "", huaweicloud.WithEndpoint(server.URL), huaweicloud.WithRetryPolicy(retry))
+	ctx := context.Background()
+
+	// The failed lookup is cached, and must not hide the zone once created.
+	if _, err := client.GetZone(ctx, "example.com."); !huaweicloud.IsNotFound(err) {
+		t.Fatalf("expected the zone to be missing, got %v", err)
+	}
+
+	created, err := client.CreateZone(ctx, huaweicloud.CreateZoneRequest{
+		Name:        "example.com",
+		Email:       "admin@example.com",
+		Ttl:         600,
+		Description: "onboarded",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Id == "" || created.Name != "example.com." || created.ZoneType != huaweicloud.ZoneTypePublic {
+		t.Errorf("unexpected created zone %+v", created)
+	}
+	if _, err := client.CreateZone(ctx, huaweicloud.CreateZoneRequest{Name: "example.com."}); !huaweicloud.IsConflict(err) {
+		t.Errorf("expected a conflict creating the zone twice, got %v", err)
+	}
+
+	server.AddRecordSet("example.com.", huaweicloud.RecordSet{Name: "www.example.com.", Type: "A", Ttl: 300, Records: []string{"192.0.2.1"}})
+	zone, err := client.GetZone(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if zone.Id != created.Id || zone.Status != huaweicloud.StatusActive || zone.Email != "admin@example.com" ||
+		zone.Ttl != 600 || zone.Description != "onboarded" || zone.RecordNum != 1 || zone.Serial == 0 || zone.PoolId == "" {
+		t.Errorf("unexpected zone %+v", zone)
+	}
+
+	updated, err := client.UpdateZone(ctx, "example.com.", huaweicloud.UpdateZoneRequest{Ttl: 3600})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Ttl != 3600 || updated.Description != "onboarded" || updated.Serial <= zone.Serial {
+		t.Errorf("unexpected updated zone %+v", updated)
+	}
+
+	deleted, err := client.DeleteZone(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted.Id != created.Id || deleted.Status != huaweicloud.StatusPendingDelete {
+		t.Errorf("unexpected deleted zone %+v", deleted)
+	}
+	if _, err := client.GetZone(ctx, "example.com."); !huaweicloud.IsNotFound(err) {
+		t.Errorf("expected the zone to be gone, got %v", err)
+	}
+}
+
+func TestClientCreatePrivateZone(t *testing.T) {
+	server := huaweicloudtest.NewServer(map[string]string{"ak": "sk"})
+	defer server.Close()
+	client := huaweicloud.NewClient("ak", "sk", "", huaweicloud.WithEndpoint(server.URL), huaweicloud.WithZoneType(huaweicloud.ZoneTypePrivate))
+	ctx := context.Background()
+
+	if _, err := client.CreateZone(ctx, huaweicloud.CreateZoneRequest{Name: "internal.example.", ZoneType: huaweicloud.ZoneTypePrivate}); err == nil {
+		t.Error("expected an error creating a private zone without a router")
+	}
+
+	router := huaweicloud.Router{RouterId: "vpc-1", RouterRegion: "cn-north-4"}
+	if _, err := client.CreateZone(ctx, huaweicloud.CreateZoneRequest{Name: "internal.example.", ZoneType: huaweicloud.ZoneTypePrivate, Router: &router}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	routers, err := client.ListZoneRouters(ctx, "internal.example.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(routers) != 1 || routers[0].RouterId != "vpc-1" {
+		t.Errorf("unexpected routers %+v", routers)
+	}
+}